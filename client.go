@@ -10,15 +10,39 @@ import (
 )
 
 type Client struct {
-	db *sql.DB
+	db      *sql.DB
+	tx      *sql.Tx
+	options options
 }
 
+// WithTx returns a copy of the Client executing all statements within the given transaction controlled by the caller
+// Delete and Reset change the schema, which implicitly commits the transaction in MySQL
+func (c *Client) WithTx(tx *sql.Tx) *Client {
+	client := *c
+	client.tx = tx
+
+	return &client
+}
+
+// Conn returns the transaction of the Client if it has one and its *sql.DB otherwise
 func (c *Client) Conn() interface{} {
+	if c.tx != nil {
+		return c.tx
+	}
+
+	return c.db
+}
+
+func (c *Client) conn() execQuerier {
+	if c.tx != nil {
+		return c.tx
+	}
+
 	return c.db
 }
 
 func (c *Client) Exists(ctx context.Context, collection string) (bool, error) {
-	err := c.db.QueryRowContext(ctx, fmt.Sprintf("SELECT * FROM `%s` LIMIT 1", collection)).Err()
+	err := c.conn().QueryRowContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 1", c.options.qualify(collection))).Err()
 	if err != nil && strings.Contains(err.Error(), "Error 1146") {
 		return false, nil
 	}
@@ -30,13 +54,13 @@ func (c *Client) Exists(ctx context.Context, collection string) (bool, error) {
 }
 
 func (c *Client) Delete(ctx context.Context, collection string) error {
-	_, err := c.db.ExecContext(ctx, "DROP TABLE IF EXISTS `"+collection+"`;")
+	_, err := c.conn().ExecContext(ctx, "DROP TABLE IF EXISTS "+c.options.qualify(collection)+";")
 
 	return err
 }
 
 func (c *Client) Reset(ctx context.Context, collection string) error {
-	_, err := c.db.ExecContext(ctx, "TRUNCATE TABLE `"+collection+"`;")
+	_, err := c.conn().ExecContext(ctx, "TRUNCATE TABLE "+c.options.qualify(collection)+";")
 
 	return err
 }
@@ -52,9 +76,9 @@ func (c *Client) Insert(ctx context.Context, collection string, values map[strin
 		placeholder = append(placeholder, "?")
 	}
 
-	_, err := c.db.ExecContext(
+	_, err := c.conn().ExecContext(
 		ctx,
-		"INSERT INTO `"+collection+"` ("+strings.Join(columns, ",")+") VALUES ("+strings.Join(placeholder, ",")+");",
+		"INSERT INTO "+c.options.qualify(collection)+" ("+strings.Join(columns, ",")+") VALUES ("+strings.Join(placeholder, ",")+");",
 		parameters...,
 	)
 
@@ -74,9 +98,9 @@ func (c *Client) Remove(ctx context.Context, collection string, identifiers map[
 		conditions = append(conditions, column+` = ?`)
 	}
 
-	_, err := c.db.ExecContext(
+	_, err := c.conn().ExecContext(
 		ctx,
-		"DELETE FROM `"+collection+"` WHERE "+strings.Join(conditions, " AND ")+";",
+		"DELETE FROM "+c.options.qualify(collection)+" WHERE "+strings.Join(conditions, " AND ")+";",
 		parameters...,
 	)
 
@@ -98,15 +122,15 @@ func (c *Client) Update(ctx context.Context, collection string, values map[strin
 		conditions = append(conditions, "`"+column+"` = ?")
 	}
 
-	_, err := c.db.ExecContext(
+	_, err := c.conn().ExecContext(
 		ctx,
-		"UPDATE `"+collection+"` SET "+strings.Join(updates, ",")+" WHERE "+strings.Join(conditions, " AND ")+";",
+		"UPDATE "+c.options.qualify(collection)+" SET "+strings.Join(updates, ",")+" WHERE "+strings.Join(conditions, " AND ")+";",
 		parameters...,
 	)
 
 	return err
 }
 
-func NewClient(db *sql.DB) *Client {
-	return &Client{db: db}
+func NewClient(db *sql.DB, opts ...Option) *Client {
+	return &Client{db: db, options: newOptions(opts)}
 }
@@ -0,0 +1,165 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+const (
+	errBadField            uint16 = 1054
+	errLockWaitTimeout     uint16 = 1205
+	errDuplicateEntry      uint16 = 1062
+	errNotAllowedCommand   uint16 = 1148
+	errNoSuchTable         uint16 = 1146
+	errLocalInfileDisabled uint16 = 3948
+)
+
+// ConcurrencyError is returned if an append conflicts with the current version of an aggregate
+type ConcurrencyError struct {
+	Stream          string
+	AggregateID     string
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (e ConcurrencyError) Error() string {
+	return fmt.Sprintf(
+		"Concurrency conflict on stream %s for aggregate %s: expected version %d, actual version %d",
+		e.Stream,
+		e.AggregateID,
+		e.ExpectedVersion,
+		e.ActualVersion,
+	)
+}
+
+// ProjectionLocked is returned if a projection lock is held by another instance
+type ProjectionLocked struct {
+	Name        string
+	Owner       string
+	LockedUntil string
+}
+
+func (e ProjectionLocked) Error() string {
+	return fmt.Sprintf("Projection %s is locked by %s until %s", e.Name, e.Owner, e.LockedUntil)
+}
+
+// UnknownEventType is returned if a loaded event has no registered type
+type UnknownEventType struct {
+	Name   string
+	Stream string
+	Number int
+}
+
+func (e UnknownEventType) Error() string {
+	return fmt.Sprintf("Unknown event type %s of event %d in stream %s", e.Name, e.Number, e.Stream)
+}
+
+// SnapshotNotFound is returned if no snapshot of the aggregate exists
+type SnapshotNotFound struct {
+	AggregateType string
+	AggregateID   string
+}
+
+func (e SnapshotNotFound) Error() string {
+	return fmt.Sprintf("Snapshot of aggregate %s %s not found", e.AggregateType, e.AggregateID)
+}
+
+// DuplicateAggregateVersion is returned if an import contains an aggregate version more than once
+type DuplicateAggregateVersion struct {
+	Stream           string
+	AggregateType    string
+	AggregateID      string
+	AggregateVersion int
+}
+
+func (e DuplicateAggregateVersion) Error() string {
+	return fmt.Sprintf(
+		"Duplicate version %d of aggregate %s %s in stream %s",
+		e.AggregateVersion,
+		e.AggregateType,
+		e.AggregateID,
+		e.Stream,
+	)
+}
+
+func isDuplicateKey(err error, keys ...string) bool {
+	var mysqlErr *driver.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != errDuplicateEntry {
+		return false
+	}
+
+	for _, key := range keys {
+		if strings.Contains(mysqlErr.Message, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLocalInfileDisabled(err error) bool {
+	var mysqlErr *driver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	return mysqlErr.Number == errNotAllowedCommand || mysqlErr.Number == errLocalInfileDisabled
+}
+
+func isUnknownColumn(err error) bool {
+	var mysqlErr *driver.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errBadField
+}
+
+func isLockWaitTimeout(err error) bool {
+	var mysqlErr *driver.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errLockWaitTimeout
+}
+
+func isMissingTable(err error) bool {
+	var mysqlErr *driver.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable
+}
+
+// MissingEventPositions is returned by loads in the global order if an event has no position,
+// it was appended without the WithGlobalOrdering option and is assigned a position by the next Install
+type MissingEventPositions struct {
+	Stream string
+	Number int
+}
+
+func (e MissingEventPositions) Error() string {
+	return fmt.Sprintf("Event %d of stream %s has no global position, append with the WithGlobalOrdering option and run Install", e.Number, e.Stream)
+}
+
+// MissingAggregateIndexes is returned by Import if the deferred aggregate indexes could not be recreated,
+// appends to the EventStream do not detect conflicting aggregate versions until the indexes are added again
+type MissingAggregateIndexes struct {
+	Stream string
+	// Err is the error of the import, if it failed before the indexes were recreated
+	Err error
+	// IndexErr prevented the indexes
+	IndexErr error
+}
+
+func (e MissingAggregateIndexes) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Import into stream %s failed: %s, its aggregate indexes are missing: %s", e.Stream, e.Err.Error(), e.IndexErr.Error())
+	}
+
+	return fmt.Sprintf("Aggregate indexes of stream %s are missing: %s", e.Stream, e.IndexErr.Error())
+}
+
+func (e MissingAggregateIndexes) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+
+	return e.IndexErr
+}
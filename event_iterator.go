@@ -3,9 +3,10 @@ package mysql
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	eventstore "github.com/go-event-store/eventstore"
@@ -13,21 +14,58 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+type streamQuery struct {
+	streamName string
+	tableName  string
+	tableKey   string
+	wheres     []string
+	values     []interface{}
+	// cursor is the highest seen number of the query
+	cursor int
+	// from is the number before the first selected event, it bounds the pages which are not continued by number
+	from int
+	// lastCreatedAt and lastNumber of the last seen event continue the pages of merged streams
+	lastCreatedAt time.Time
+	lastNumber    int
+}
+
+type fetchedEvent struct {
+	event *eventstore.DomainEvent
+	// query is the index of the query which selected the event
+	query     int
+	stream    string
+	number    int
+	position  int
+	createdAt time.Time
+}
+
 type DomainEventIterator struct {
-	limit        int
-	offset       int
-	count        int
-	position     int
-	length       int
-	done         bool
-	err          error
-	current      *eventstore.DomainEvent
-	events       []*eventstore.DomainEvent
-	db           *sql.DB
-	typeRegistry eventstore.TypeRegistry
-	query        string
-	parameters   []interface{}
-	ctx          context.Context
+	limit          int
+	count          int
+	position       int
+	length         int
+	done           bool
+	err            error
+	current        *eventstore.DomainEvent
+	events         []*eventstore.DomainEvent
+	db             *sql.DB
+	typeRegistry   eventstore.TypeRegistry
+	queries        []*streamQuery
+	ctx            context.Context
+	positionsTable string
+	lastPosition   int
+	gapDetection   *GapDetection
+	serializers    serializers
+	upcasters      *Upcasters
+	unknownEvents  UnknownEventPolicy
+	// eventNames maps stored event names to the names of the registered types
+	eventNames map[string]string
+	// prooph rows contain empty PHP arrays for empty payloads and metadata
+	prooph bool
+	// rawQuery is paged with OFFSET by iterators of NewDomainEventIterator
+	rawQuery      string
+	rawParameters []interface{}
+	offset        int
 }
 
 func (it *DomainEventIterator) Next() bool {
@@ -38,7 +76,9 @@ func (it *DomainEventIterator) Next() bool {
 		return true
 	}
 
-	it.fetchEvents()
+	for !it.done && it.err == nil && it.length < it.position+1 {
+		it.fetchEvents()
+	}
 
 	if it.length >= it.position+1 {
 		it.current = it.events[it.position]
@@ -90,7 +130,7 @@ func (it *DomainEventIterator) ToList() ([]eventstore.DomainEvent, error) {
 		list = append(list, *it.current)
 	}
 
-	return list, nil
+	return list, it.err
 }
 
 func (it *DomainEventIterator) fetchEvents() {
@@ -98,76 +138,430 @@ func (it *DomainEventIterator) fetchEvents() {
 		return
 	}
 
-	query := it.query
+	if it.rawQuery != "" {
+		it.fetchRawEvents()
+		return
+	}
+
+	// streams of the AggregateTypeTable strategy have no tables before their first append
+	if len(it.queries) == 0 {
+		it.done = true
+		return
+	}
+
 	limit := it.limit
 
 	if it.count > 0 && it.count < it.limit {
 		limit = it.count
 	}
 
-	query = fmt.Sprintf("%s LIMIT %d OFFSET %d", query, limit, it.offset)
+	// gaps which were already waited for are not checked again, their events may be ordered after the following events
+	waited := map[gapRange]bool{}
+
+	for {
+		query, parameters := it.buildQuery(limit)
+
+		rows, err := it.db.QueryContext(it.ctx, query, parameters...)
+		if err != nil {
+			it.err = err
+			return
+		}
+
+		events, counter := it.readRows(rows)
+		rows.Close()
+
+		// the numbers of pages ordered by global position are not ascending, concurrent appends commit their positions in order
+		if it.gapDetection == nil || it.positionsTable != "" {
+			it.accept(events, counter < limit)
+			return
+		}
 
-	rows, err := it.db.QueryContext(it.ctx, query, it.parameters...)
+		gap, filled, err := it.detectGap(events, waited)
+		if err != nil {
+			it.err = err
+			return
+		}
+
+		if gap == -1 {
+			it.accept(events, counter < limit)
+			return
+		}
+
+		// the transaction of the gap did not end within the timeout, the iteration stops before the gap
+		if !filled {
+			it.accept(events[:gap], true)
+			return
+		}
+
+		// the gap was filled by a committed transaction, the page is queried again to include its events
+	}
+}
+
+func (it *DomainEventIterator) fetchRawEvents() {
+	limit := it.limit
+
+	if it.count > 0 && it.count < it.limit {
+		limit = it.count
+	}
+
+	query := fmt.Sprintf("%s LIMIT %d OFFSET %d", it.rawQuery, limit, it.offset)
+
+	rows, err := it.db.QueryContext(it.ctx, query, it.rawParameters...)
 	if err != nil {
 		it.err = err
 		return
 	}
 
-	counter := it.appendRows(rows)
+	events, counter := it.readRows(rows)
+	rows.Close()
 
-	if counter < limit {
-		it.done = true
+	it.offset += counter
+	it.accept(events, counter < limit)
+}
+
+func (it *DomainEventIterator) accept(events []fetchedEvent, done bool) {
+	it.done = done
+
+	for i, ev := range events {
+		it.advance(ev)
+
+		// upcasted events of a single row share its number and count as one row
+		if it.count > 0 && (i == 0 || ev.number != events[i-1].number || ev.query != events[i-1].query) {
+			it.count--
+
+			if it.count == 0 {
+				it.done = true
+			}
+		}
+
+		// skipped unknown events only move the cursor
+		if ev.event == nil {
+			continue
+		}
+
+		it.events = append(it.events, ev.event)
+		it.length++
 	}
+}
 
-	it.count -= counter
-	it.offset += counter
-	it.length += counter
+// errGapPending is returned by confirmGap if the transactions of a gap did not end within the timeout
+var errGapPending = errors.New("Gap is pending")
+
+// gapRange are the missing numbers between two numbers of a query
+type gapRange struct {
+	query int
+	from  int
+	to    int
 }
 
-func (it *DomainEventIterator) appendRows(rows *sql.Rows) int {
+// detectGap waits for the transactions of gaps between the numbers of unfiltered streams and returns the index of the first event
+// after a gap which was filled or is still pending after the timeout, gaps of rolled back transactions are skipped
+func (it *DomainEventIterator) detectGap(events []fetchedEvent, waited map[gapRange]bool) (int, bool, error) {
+	last := map[int]int{}
+	for i, q := range it.queries {
+		if len(q.wheres) == 0 {
+			last[i] = q.cursor
+		}
+	}
+
+	for i, ev := range events {
+		number, ok := last[ev.query]
+		if !ok {
+			continue
+		}
+
+		if number < 0 {
+			number = 0
+		}
+
+		gap := gapRange{query: ev.query, from: number, to: ev.number}
+
+		if ev.number > number+1 && !waited[gap] {
+			waited[gap] = true
+
+			filled, err := it.confirmGap(gap)
+			if err == errGapPending {
+				return i, false, nil
+			}
+			if err != nil {
+				return -1, false, err
+			}
+			if filled {
+				return i, true, nil
+			}
+		}
+
+		if ev.number > number {
+			last[ev.query] = ev.number
+		}
+	}
+
+	return -1, false, nil
+}
+
+// confirmGap waits with a locking read until the transactions inserting the missing numbers end,
+// it returns whether committed events fill the gap and errGapPending if the transactions did not end within the timeout
+func (it *DomainEventIterator) confirmGap(gap gapRange) (bool, error) {
+	ctx, cancel := context.WithTimeout(it.ctx, it.gapDetection.Timeout)
+	defer cancel()
+
+	rows, err := it.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT no FROM %s WHERE no > ? AND no < ? FOR SHARE`, it.queries[gap.query].tableName),
+		gap.from,
+		gap.to,
+	)
+	if err != nil && it.ctx.Err() == nil && (ctx.Err() != nil || isLockWaitTimeout(err)) {
+		return false, errGapPending
+	}
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	filled := rows.Next()
+
+	return filled, rows.Err()
+}
+
+// buildQuery selects the next page after the last seen number of each query,
+// so every page is a range seek on the primary key instead of an OFFSET scan
+// Merged streams are ordered by creation time, their pages continue after the creation time and number of the last seen event
+// If a positions table is set, the page continues after the last seen global position instead
+func (it *DomainEventIterator) buildQuery(limit int) (string, []interface{}) {
+	queries := make([]string, 0, len(it.queries))
+	parameters := make([]interface{}, 0)
+
+	// the queries of a single stream read its tables, whose rows are numbered across all of them
+	merged := false
+	for _, q := range it.queries {
+		if q.streamName != it.queries[0].streamName {
+			merged = true
+		}
+	}
+
+	order := "no ASC"
+	if merged {
+		order = "created_at ASC, no ASC"
+	}
+	if it.positionsTable != "" {
+		order = "position ASC"
+	}
+
+	for i, q := range it.queries {
+		wheres := append([]string{}, q.wheres...)
+
+		parameters = append(parameters, q.streamName, i)
+
+		if it.positionsTable == "" && !merged {
+			wheres = append(wheres, `no > ?`)
+
+			parameters = append(parameters, q.values...)
+			parameters = append(parameters, q.cursor)
+
+			queries = append(queries, fmt.Sprintf(
+				`SELECT no, event_id, event_name, payload, metadata, created_at, ? as stream, ? as query, 0 as position FROM %s WHERE %s ORDER BY no ASC LIMIT %d`,
+				q.tableName,
+				strings.Join(wheres, " AND "),
+				limit,
+			))
+
+			continue
+		}
+
+		if it.positionsTable == "" {
+			wheres = append(wheres, `no > ?`)
+
+			parameters = append(parameters, q.values...)
+			parameters = append(parameters, q.from)
+
+			// the creation time does not have to follow the number within a stream
+			if !q.lastCreatedAt.IsZero() {
+				wheres = append(wheres, `(created_at > ? OR (created_at = ? AND no > ?))`)
+				parameters = append(parameters, q.lastCreatedAt, q.lastCreatedAt, q.lastNumber)
+			}
+
+			queries = append(queries, fmt.Sprintf(
+				`SELECT no, event_id, event_name, payload, metadata, created_at, ? as stream, ? as query, 0 as position FROM %s WHERE %s ORDER BY created_at ASC, no ASC LIMIT %d`,
+				q.tableName,
+				strings.Join(wheres, " AND "),
+				limit,
+			))
+
+			continue
+		}
+
+		wheres = append(wheres, `no > ?`, `p.position > ?`)
+
+		parameters = append(parameters, q.tableKey)
+		parameters = append(parameters, q.values...)
+		// concurrent appends may commit a lower number with a higher position, so only the position continues the pages
+		parameters = append(parameters, q.from, it.lastPosition)
+
+		queries = append(queries, fmt.Sprintf(
+			`SELECT no, event_id, event_name, payload, metadata, created_at, ? as stream, ? as query, p.position FROM %s INNER JOIN %s p ON p.event_table = ? AND p.event_no = no WHERE %s ORDER BY p.position ASC LIMIT %d`,
+			q.tableName,
+			it.positionsTable,
+			strings.Join(wheres, " AND "),
+			limit,
+		))
+	}
+
+	if len(queries) == 1 {
+		return queries[0], parameters
+	}
+
+	return fmt.Sprintf("(%s) ORDER BY %s LIMIT %d", strings.Join(queries, ") UNION ALL ("), order, limit), parameters
+}
+
+func (it *DomainEventIterator) advance(ev fetchedEvent) {
+	if it.lastPosition < ev.position {
+		it.lastPosition = ev.position
+	}
+
+	// events of raw queries are not selected by a query
+	if ev.query >= len(it.queries) {
+		return
+	}
+
+	q := it.queries[ev.query]
+
+	if q.cursor < ev.number {
+		q.cursor = ev.number
+	}
+
+	q.lastCreatedAt = ev.createdAt
+	q.lastNumber = ev.number
+}
+
+func (it *DomainEventIterator) readRows(rows *sql.Rows) ([]fetchedEvent, int) {
+	events := []fetchedEvent{}
 	counter := 0
 
+	columns, err := rows.Columns()
+	if err != nil {
+		it.err = err
+		return events, counter
+	}
+
 	for rows.Next() {
-		var name, eventID, stream string
-		var number int
-		var payload []byte
-		var createdAt time.Time
-		var metadata map[string]interface{}
+		var raw RawEvent
+		var query, position int
 
-		var metastring []byte
+		dest := []interface{}{&raw.Number, &raw.EventID, &raw.Name, &raw.Payload, &raw.Metadata, &raw.CreatedAt, &raw.Stream, &query, &position}
 
-		it.err = rows.Scan(&number, &eventID, &name, &payload, &metastring, &createdAt, &stream)
+		// raw queries of NewDomainEventIterator select the event columns and the stream only
+		if len(columns) < len(dest) {
+			dest = dest[:len(columns)]
+		}
+
+		it.err = rows.Scan(dest...)
 		if it.err != nil {
-			return counter
+			return events, counter
+		}
+
+		counter++
+
+		if name, ok := it.eventNames[raw.Name]; ok {
+			raw.Name = name
+		}
+
+		if it.prooph {
+			raw.Payload = proophObject(raw.Payload)
+			raw.Metadata = proophObject(raw.Metadata)
+		}
+
+		raws := []RawEvent{raw}
+
+		if it.upcasters != nil {
+			raws, it.err = it.upcasters.Upcast(raw)
+			if it.err != nil {
+				return events, counter
+			}
+		}
+
+		for _, raw := range raws {
+			event, err := it.decode(raw, position)
+			if err != nil {
+				it.err = err
+				return events, counter
+			}
+
+			events = append(events, fetchedEvent{
+				event:     event,
+				query:     query,
+				stream:    raw.Stream,
+				number:    raw.Number,
+				position:  position,
+				createdAt: raw.CreatedAt,
+			})
 		}
+	}
 
-		eventType, _ := it.typeRegistry.GetTypeByName(name)
+	return events, counter
+}
+
+// decode returns nil without error for unknown events which are skipped by the UnknownEventPolicy
+func (it *DomainEventIterator) decode(raw RawEvent, position int) (*eventstore.DomainEvent, error) {
+	eventType, ok := it.typeRegistry.GetTypeByName(raw.Name)
+	if !ok && it.unknownEvents == UnknownEventSkip {
+		return nil, nil
+	}
+	if !ok && it.unknownEvents == UnknownEventError {
+		return nil, UnknownEventType{Name: raw.Name, Stream: raw.Stream, Number: raw.Number}
+	}
+
+	metadata, err := it.serializers.decodeMetadata(raw.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
-		json.Unmarshal(metastring, &metadata)
+	var payload interface{} = raw
+
+	if ok {
+		codec, _ := metadata[CodecMetadataKey].(string)
 
 		eventValue := reflect.New(eventType)
-		eventInterface := eventValue.Interface()
-		it.err = json.Unmarshal(payload, eventInterface)
+		err = it.serializers.decodePayload(raw.Payload, codec, eventValue.Interface())
+		if err != nil {
+			return nil, err
+		}
 
-		metadata["stream"] = stream
+		payload = reflect.Indirect(eventValue).Interface()
+	}
 
-		event := eventstore.
-			NewDomainEvent(uuid.NewV4(), reflect.Indirect(eventValue).Interface(), metadata, createdAt).
-			WithUUID(uuid.FromStringOrNil(eventID)).
-			WithNumber(number)
+	// the codec is an internal detail of the stored row
+	delete(metadata, CodecMetadataKey)
 
-		it.events = append(it.events, &event)
+	metadata["stream"] = raw.Stream
 
-		counter++
+	if it.positionsTable != "" {
+		metadata["position"] = position
 	}
 
-	return counter
+	event := eventstore.
+		NewDomainEvent(uuid.NewV4(), payload, metadata, raw.CreatedAt).
+		WithUUID(uuid.FromStringOrNil(raw.EventID)).
+		WithNumber(raw.Number)
+
+	return &event, nil
 }
 
+// NewDomainEventIterator pages the given query with OFFSET, it has to select
+// no, event_id, event_name, payload, metadata, created_at and the stream name
+//
+// Deprecated: OFFSET pages skip or repeat events of streams which are appended while iterating, use the Load methods of the PersistenceStrategy instead
 func NewDomainEventIterator(ctx context.Context, db *sql.DB, query string, parameters []interface{}, count int) *DomainEventIterator {
+	it := newDomainEventIterator(ctx, db, eventstore.NewTypeRegistry(), nil, count)
+	it.rawQuery = query
+	it.rawParameters = parameters
+
+	return it
+}
+
+func newDomainEventIterator(ctx context.Context, db *sql.DB, typeRegistry eventstore.TypeRegistry, queries []*streamQuery, count int) *DomainEventIterator {
 	return &DomainEventIterator{
 		limit:        1000,
-		offset:       0,
 		count:        count,
 		position:     -1,
 		length:       0,
@@ -175,9 +569,9 @@ func NewDomainEventIterator(ctx context.Context, db *sql.DB, query string, param
 		done:         false,
 		events:       make([]*eventstore.DomainEvent, 0),
 		db:           db,
-		query:        query,
-		parameters:   parameters,
-		typeRegistry: eventstore.NewTypeRegistry(),
+		queries:      queries,
+		typeRegistry: typeRegistry,
+		serializers:  newSerializers(),
 		ctx:          ctx,
 	}
 }
@@ -0,0 +1,290 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	eventstore "github.com/go-event-store/eventstore"
+	driver "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ImportOptions configures PersistenceStrategy.Import
+type ImportOptions struct {
+	// BatchSize is the number of events loaded per LOAD DATA statement, the default is 10000 and the maximum 65534
+	BatchSize int
+	// DeferIndexes drops the aggregate indexes of the stream table during the import and recreates them afterwards,
+	// also if the import fails, MissingAggregateIndexes is returned if they cannot be recreated
+	DeferIndexes bool
+	// Validate checks the uniqueness of the imported aggregate versions before the deferred indexes are recreated
+	Validate bool
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+
+// Import loads the events into an existing EventStream with LOAD DATA LOCAL INFILE, it is meant to seed new EventStreams from an export
+// Each batch is committed on its own, so a failed import leaves the already loaded batches behind
+// If local infile is disabled on the server, the remaining events are appended with batched inserts instead
+func (ps PersistenceStrategy) Import(ctx context.Context, streamName string, events eventstore.DomainEventIterator, opts ImportOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+	// global positions and outbox messages of a batch are selected by their event ids
+	if opts.BatchSize >= maxPlaceholders {
+		opts.BatchSize = maxPlaceholders - 1
+	}
+
+	schema, err := ps.FetchStreamSchema(ctx, streamName)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.DeferIndexes && ps.options.tableStrategy != TablePerStream {
+		return 0, fmt.Errorf("Indexes of the shared table of stream %s cannot be deferred", streamName)
+	}
+	if (opts.DeferIndexes || opts.Validate) && schema.Kind == SimpleSchema {
+		return 0, fmt.Errorf("Stream %s of schema kind %s has no aggregate versions to defer or validate", streamName, schema.Kind)
+	}
+
+	table := ps.streamTable(streamName)
+
+	if opts.DeferIndexes {
+		_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DROP INDEX ix_unique_event, DROP INDEX ix_query_aggregate`, table.name))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	imported, err := ps.importEvents(ctx, streamName, table, events, opts.BatchSize)
+
+	if err == nil && opts.Validate {
+		err = ps.validateImport(ctx, streamName)
+	}
+
+	if !opts.DeferIndexes {
+		return imported, err
+	}
+
+	// the indexes guard concurrent appends, so they are recreated after a failed or canceled import as well
+	// duplicate aggregate versions prevent the unique index, the EventStream has to be repaired or deleted then
+	_, indexErr := ps.db.ExecContext(context.Background(), fmt.Sprintf(
+		`ALTER TABLE %s ADD UNIQUE KEY ix_unique_event (aggregate_type, aggregate_id, aggregate_version), ADD KEY ix_query_aggregate (aggregate_type,aggregate_id,no)`,
+		table.name,
+	))
+	if indexErr != nil {
+		return imported, MissingAggregateIndexes{Stream: streamName, Err: err, IndexErr: indexErr}
+	}
+
+	return imported, err
+}
+
+// importEvents appends the events of shared tables with batched inserts, their numbers are assigned while the EventStream is locked
+func (ps PersistenceStrategy) importEvents(ctx context.Context, streamName string, table streamTable, events eventstore.DomainEventIterator, batchSize int) (int, error) {
+	imported := 0
+	localInfile := ps.options.tableStrategy == TablePerStream
+	batch := make([]eventstore.DomainEvent, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		var err error
+
+		if localInfile {
+			err = ps.loadBatch(ctx, streamName, table, batch)
+			if isLocalInfileDisabled(err) {
+				localInfile = false
+			}
+		}
+
+		if !localInfile {
+			err = ps.AppendTo(ctx, streamName, batch)
+		}
+		if err != nil {
+			return err
+		}
+
+		imported += len(batch)
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for events.Next() {
+		ev, err := events.Current()
+		if err != nil {
+			return imported, err
+		}
+
+		batch = append(batch, *ev)
+
+		if len(batch) == batchSize {
+			err = flush()
+			if err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	if events.Error() != nil {
+		return imported, events.Error()
+	}
+
+	return imported, flush()
+}
+
+// loadBatch streams the batch as tab separated rows through a registered reader handler of the driver
+// LOCAL implies IGNORE for duplicate keys, so skipped rows are reported as duplicates
+func (ps PersistenceStrategy) loadBatch(ctx context.Context, streamName string, table streamTable, events []eventstore.DomainEvent) error {
+	rows, err := ps.encodeEvents(events)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+
+	for _, row := range rows {
+		buffer.WriteString(strings.Join([]string{
+			tsvEscaper.Replace(row.event.UUID().String()),
+			tsvEscaper.Replace(row.name),
+			tsvEscaper.Replace(string(row.payload)),
+			tsvEscaper.Replace(string(row.metadata)),
+			row.event.CreatedAt().UTC().Format("2006-01-02 15:04:05.000000"),
+		}, "\t"))
+		buffer.WriteByte('\n')
+	}
+
+	handler := "import-" + uuid.NewV4().String()
+	driver.RegisterReaderHandler(handler, func() io.Reader { return &buffer })
+	defer driver.DeregisterReaderHandler(handler)
+
+	// the events, their positions and outbox messages are committed together, the positions lock is held until the commit
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last int
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(no), 0) FROM %s`, table.name)).Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	r, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n' (event_id, event_name, payload, metadata, created_at)`,
+		handler,
+		table.name,
+	))
+	if err != nil {
+		return err
+	}
+
+	count, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	loaded := rows
+	if int(count) != len(rows) {
+		loaded, err = ps.loadedRows(ctx, tx, table, rows, last)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(loaded) > 0 {
+		err = ps.appendChunk(ctx, tx, streamName, table, loaded)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the loaded rows of a batch with duplicates are committed before the duplicates are reported
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if int(count) != len(rows) {
+		return fmt.Errorf("Import into stream %s skipped %d duplicate events", streamName, len(rows)-int(count))
+	}
+
+	return nil
+}
+
+// loadedRows returns the rows of the batch which were loaded after the given number, skipped duplicates are either missing or older
+func (ps PersistenceStrategy) loadedRows(ctx context.Context, conn execQuerier, table streamTable, rows []appendRow, last int) ([]appendRow, error) {
+	placeholder := make([]string, 0, len(rows))
+	parameters := make([]interface{}, 0, len(rows)+1)
+
+	for _, row := range rows {
+		placeholder = append(placeholder, "?")
+		parameters = append(parameters, row.event.UUID().String())
+	}
+
+	where, parameters := table.where([]string{fmt.Sprintf(`event_id IN (%s)`, strings.Join(placeholder, ",")), `no > ?`}, append(parameters, last)...)
+
+	result, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT event_id FROM %s WHERE %s`, table.name, where), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	ids := map[string]bool{}
+
+	for result.Next() {
+		var id string
+
+		err = result.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[id] = true
+	}
+
+	loaded := []appendRow{}
+	for _, row := range rows {
+		if ids[row.event.UUID().String()] {
+			loaded = append(loaded, row)
+		}
+	}
+
+	return loaded, result.Err()
+}
+
+func (ps PersistenceStrategy) validateImport(ctx context.Context, streamName string) error {
+	tables, err := ps.streamTables(ctx, ps.db, streamName)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		duplicate := DuplicateAggregateVersion{Stream: streamName}
+
+		where, values := table.where(nil)
+
+		err = ps.db.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT aggregate_type, aggregate_id, aggregate_version FROM %s WHERE %s GROUP BY aggregate_type, aggregate_id, aggregate_version HAVING COUNT(*) > 1 LIMIT 1`,
+			table.name,
+			where,
+		), values...).Scan(&duplicate.AggregateType, &duplicate.AggregateID, &duplicate.AggregateVersion)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		return duplicate
+	}
+
+	return nil
+}
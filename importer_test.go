@@ -0,0 +1,164 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlImport(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type TestEvent struct {
+		Foo string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(TestEvent{})
+
+	ps := mysql.NewPersistenceStrategy(db)
+	eventStore := eventstore.NewEventStore(ps)
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	export := func(t *testing.T, events []eventstore.DomainEvent) eventstore.DomainEventIterator {
+		err := eventStore.CreateStream(ctx, "export-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ev := range events {
+			err = eventStore.AppendTo(ctx, "export-stream", []eventstore.DomainEvent{ev})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		it, err := eventStore.Load(ctx, "export-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return it
+	}
+
+	t.Run("Import events with deferred indexes", func(t *testing.T) {
+		aggregateID := uuid.NewV4()
+
+		events := []eventstore.DomainEvent{}
+		for i := 1; i <= 25; i++ {
+			events = append(events, eventstore.NewDomainEvent(aggregateID, TestEvent{Foo: "tab\tnew\nline\\"}, nil, time.Now()).WithVersion(i))
+		}
+
+		it := export(t, events)
+		defer eventStore.DeleteStream(ctx, "export-stream")
+
+		err := eventStore.CreateStream(ctx, "import-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "import-stream")
+
+		imported, err := ps.Import(ctx, "import-stream", it, mysql.ImportOptions{BatchSize: 10, DeferIndexes: true, Validate: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported != 25 {
+			t.Fatalf("Expected 25 imported events, got %d", imported)
+		}
+
+		it, err = eventStore.Load(ctx, "import-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 25 {
+			t.Fatalf("Expected 25 events, got %d", len(list))
+		}
+
+		for i, ev := range list {
+			if ev.Version() != i+1 || ev.Payload().(TestEvent).Foo != "tab\tnew\nline\\" {
+				t.Fatalf("Unexpected event %d with version %d and payload %v", i, ev.Version(), ev.Payload())
+			}
+		}
+
+		err = ps.AppendTo(ctx, "import-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(25),
+		})
+		if _, ok := err.(mysql.ConcurrencyError); !ok {
+			t.Errorf("Expected recreated unique index, got %v", err)
+		}
+	})
+
+	t.Run("Validation reports duplicate aggregate versions", func(t *testing.T) {
+		aggregateID := uuid.NewV4()
+
+		it := export(t, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(1),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()).WithVersion(1),
+		})
+		defer eventStore.DeleteStream(ctx, "export-stream")
+
+		err := eventStore.CreateStream(ctx, "import-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "import-stream")
+
+		err = ps.AppendTo(ctx, "import-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = ps.Import(ctx, "import-stream", it, mysql.ImportOptions{DeferIndexes: true, Validate: true})
+		if _, ok := err.(mysql.MissingAggregateIndexes); !ok {
+			t.Errorf("Expected MissingAggregateIndexes error, got %v", err)
+		}
+
+		var duplicate mysql.DuplicateAggregateVersion
+		if !errors.As(err, &duplicate) || duplicate.AggregateID != aggregateID.String() {
+			t.Errorf("Expected DuplicateAggregateVersion error, got %v", err)
+		}
+	})
+
+	t.Run("Simple streams have no indexes to defer", func(t *testing.T) {
+		it := export(t, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		defer eventStore.DeleteStream(ctx, "export-stream")
+
+		err := ps.CreateStream(ctx, "import-stream", mysql.WithSchemaKind(mysql.SimpleSchema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "import-stream")
+
+		_, err = ps.Import(ctx, "import-stream", it, mysql.ImportOptions{DeferIndexes: true})
+		if err == nil {
+			t.Error("Expected error for deferred indexes of a simple stream")
+		}
+	})
+}
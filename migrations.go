@@ -0,0 +1,305 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+const SchemaMigrationsTable = "schema_migrations"
+
+const (
+	errDuplicateColumn  uint16 = 1060
+	errDuplicateKeyName uint16 = 1061
+	errCantDropField    uint16 = 1091
+)
+
+// SchemaTables contains the qualified names of the fixed EventStore tables
+type SchemaTables struct {
+	EventStreams   string
+	Projections    string
+	EventPositions string
+}
+
+// Migration evolves the EventStore schema to the given version
+type Migration struct {
+	Version     int
+	Description string
+	// Tables returns the statements for the fixed tables
+	Tables func(tables SchemaTables) []string
+	// Streams returns the statements for a single EventStream table, it is called for every stream in the EventStreams table
+	Streams func(table string) []string
+	// Extension marks changes which are not part of the prooph schema, they are skipped in the prooph compatibility mode
+	Extension bool
+}
+
+// Migrations are the built in migrations from the initial schema to the current one
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Add locked_by to projections",
+		Extension:   true,
+		Tables: func(tables SchemaTables) []string {
+			return []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN locked_by VARCHAR(150) AFTER locked_until`, tables.Projections),
+			}
+		},
+	},
+	{
+		Version:     2,
+		Description: "Convert event_streams and projections to utf8mb4",
+		Tables: func(tables SchemaTables) []string {
+			return []string{
+				fmt.Sprintf(`ALTER TABLE %s CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin`, tables.EventStreams),
+				fmt.Sprintf(`ALTER TABLE %s CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin`, tables.Projections),
+			}
+		},
+	},
+	{
+		Version:     3,
+		Description: "Record the schema of event streams",
+		Extension:   true,
+		Tables: func(tables SchemaTables) []string {
+			return []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN stream_schema JSON AFTER metadata`, tables.EventStreams),
+			}
+		},
+	},
+}
+
+// Migrator applies all pending Migrations and records the applied versions in the SchemaMigrationsTable
+// Statements which fail because a column or index already exists or is already dropped are ignored,
+// so partially applied migrations can be re-run
+type Migrator struct {
+	db         *sql.DB
+	options    options
+	migrations []Migration
+}
+
+// Register additional migrations
+func (m *Migrator) Register(migrations ...Migration) {
+	m.migrations = append(m.migrations, migrations...)
+}
+
+// Version returns the latest applied migration version
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	err := m.createMigrationsTable(ctx, m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.version(ctx, m.db)
+}
+
+// DryRun returns all statements of the pending migrations without executing them, it does not change the database
+func (m *Migrator) DryRun(ctx context.Context) ([]string, error) {
+	return m.run(ctx, true)
+}
+
+// Migrate applies all pending migrations and returns the executed statements
+func (m *Migrator) Migrate(ctx context.Context) ([]string, error) {
+	return m.run(ctx, false)
+}
+
+func (m *Migrator) run(ctx context.Context, dryRun bool) ([]string, error) {
+	statements := []string{}
+
+	migrations, err := m.sortedMigrations()
+	if err != nil {
+		return statements, err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return statements, err
+	}
+	defer conn.Close()
+
+	// a dry run only reads the applied version, without the migrations table nothing is applied yet
+	if !dryRun {
+		lockName := m.options.qualify(m.options.schemaMigrationsTableName)
+
+		var locked sql.NullInt64
+		err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 60)`, lockName).Scan(&locked)
+		if err != nil {
+			return statements, err
+		}
+		if locked.Int64 != 1 {
+			return statements, fmt.Errorf("Unable to acquire migration lock %s", lockName)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, lockName)
+
+		err = m.createMigrationsTable(ctx, conn)
+		if err != nil {
+			return statements, err
+		}
+	}
+
+	version, err := m.version(ctx, conn)
+	if dryRun && isMissingTable(err) {
+		version, err = 0, nil
+	}
+	if err != nil {
+		return statements, err
+	}
+
+	streamTables, err := m.streamTables(ctx, conn)
+	if err != nil {
+		return statements, err
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		steps := m.statements(migration, streamTables)
+		statements = append(statements, fmt.Sprintf("-- %d: %s", migration.Version, migration.Description))
+		statements = append(statements, steps...)
+
+		if dryRun {
+			continue
+		}
+
+		for _, step := range steps {
+			_, err = conn.ExecContext(ctx, step)
+			if err != nil && !isAlreadyApplied(err) {
+				return statements, fmt.Errorf("Migration %d failed: %s", migration.Version, err.Error())
+			}
+		}
+
+		_, err = conn.ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s (version, description, applied_at) VALUES (?, ?, UTC_TIMESTAMP(6))`, m.options.qualify(m.options.schemaMigrationsTableName)),
+			migration.Version,
+			migration.Description,
+		)
+		if err != nil {
+			return statements, err
+		}
+	}
+
+	return statements, nil
+}
+
+func (m *Migrator) statements(migration Migration, streamTables []string) []string {
+	statements := []string{}
+
+	if migration.Extension && m.options.prooph != nil {
+		return statements
+	}
+
+	if migration.Tables != nil {
+		statements = append(statements, migration.Tables(SchemaTables{
+			EventStreams:   m.options.streamsTable(),
+			Projections:    m.options.projectionsTable(),
+			EventPositions: m.options.positionsTable(),
+		})...)
+	}
+
+	if migration.Streams != nil {
+		for _, table := range streamTables {
+			statements = append(statements, migration.Streams(m.options.qualify(table))...)
+		}
+	}
+
+	return statements
+}
+
+func (m *Migrator) sortedMigrations() ([]Migration, error) {
+	migrations := append([]Migration{}, m.migrations...)
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return migrations, fmt.Errorf("Duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func (m *Migrator) createMigrationsTable(ctx context.Context, conn execQuerier) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+            version INT(11) NOT NULL,
+            description VARCHAR(255) NOT NULL,
+            applied_at DATETIME(6) NOT NULL,
+            PRIMARY KEY (version)
+          ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, m.options.qualify(m.options.schemaMigrationsTableName)))
+
+	return err
+}
+
+func (m *Migrator) version(ctx context.Context, conn execQuerier) (int, error) {
+	var version int
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s`, m.options.qualify(m.options.schemaMigrationsTableName))).Scan(&version)
+
+	return version, err
+}
+
+func (m *Migrator) streamTables(ctx context.Context, conn execQuerier) ([]string, error) {
+	tables := []string{}
+
+	if m.options.tableStrategy == SingleTable {
+		return []string{m.options.eventsTableName}, nil
+	}
+
+	where := `TRUE`
+	if m.options.tableStrategy == AggregateTypeTable {
+		where = `real_stream_name LIKE '` + likeEscaper.Replace(aggregateTablePrefix) + `%'`
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT stream_name FROM %s WHERE %s ORDER BY no ASC`, m.options.streamsTable(), where))
+	// an EventStore which is not installed yet has no stream tables
+	if isMissingTable(err) {
+		return tables, nil
+	}
+	if err != nil {
+		return tables, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+
+		err = rows.Scan(&table)
+		if err != nil {
+			return []string{}, err
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+func isAlreadyApplied(err error) bool {
+	var mysqlErr *driver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case errDuplicateColumn, errDuplicateKeyName, errCantDropField:
+		return true
+	}
+
+	return false
+}
+
+func NewMigrator(db *sql.DB, opts ...Option) *Migrator {
+	return &Migrator{
+		db:         db,
+		options:    newOptions(opts),
+		migrations: append([]Migration{}, Migrations...),
+	}
+}
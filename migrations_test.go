@@ -0,0 +1,138 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func Test_MysqlMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	eventStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db))
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("Migrate fixed and stream tables", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		migrator := mysql.NewMigrator(db, mysql.WithSchemaMigrationsTable("test_schema_migrations"))
+		defer db.ExecContext(ctx, "DROP TABLE test_schema_migrations")
+
+		migrator.Register(mysql.Migration{
+			Version:     100,
+			Description: "Add created_at index to streams",
+			Streams: func(table string) []string {
+				return []string{fmt.Sprintf(`ALTER TABLE %s ADD KEY ix_created_at (created_at)`, table)}
+			},
+		})
+
+		statements, err := migrator.DryRun(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := fmt.Sprintf("ALTER TABLE `%s` ADD KEY ix_created_at (created_at)", mysql.GenerateTableName("foo-stream"))
+		if !strings.Contains(strings.Join(statements, "\n"), expected) {
+			t.Fatalf("Expected stream statement in dry run, got %v", statements)
+		}
+
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 0 {
+			t.Fatalf("Expected no applied migration after dry run, got version %d", version)
+		}
+
+		_, err = migrator.Migrate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		version, err = migrator.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 100 {
+			t.Fatalf("Expected version 100, got %d", version)
+		}
+
+		statements, err = migrator.Migrate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statements) != 0 {
+			t.Fatalf("Expected no pending migrations, got %v", statements)
+		}
+	})
+
+	t.Run("Re-run of partially applied migrations", func(t *testing.T) {
+		migrator := mysql.NewMigrator(db, mysql.WithSchemaMigrationsTable("test_schema_migrations"))
+		defer db.ExecContext(ctx, "DROP TABLE test_schema_migrations")
+
+		_, err := migrator.Migrate(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = db.ExecContext(ctx, "DELETE FROM test_schema_migrations")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = migrator.Migrate(ctx)
+		if err != nil {
+			t.Fatalf("Expected idempotent re-run, got %v", err)
+		}
+	})
+	t.Run("Dry run does not change an uninstalled EventStore", func(t *testing.T) {
+		migrator := mysql.NewMigrator(
+			db,
+			mysql.WithEventStreamsTable("dry_run_event_streams"),
+			mysql.WithSchemaMigrationsTable("dry_run_schema_migrations"),
+		)
+
+		statements, err := migrator.DryRun(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statements) == 0 {
+			t.Fatal("Expected pending migrations in dry run")
+		}
+
+		var tables int
+
+		err = db.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME IN ('dry_run_event_streams', 'dry_run_schema_migrations')",
+		).Scan(&tables)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tables != 0 {
+			t.Fatalf("Expected no tables created by the dry run, got %d", tables)
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package mysql
+
+import (
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+const defaultTablePrefix = "_"
+
+// Option configures a PersistenceStrategy, ProjectionManager or Client
+type Option func(*options)
+
+type options struct {
+	database                  string
+	tablePrefix               string
+	eventStreamsTableName     string
+	projectionsTableName      string
+	eventPositionsTableName   string
+	schemaMigrationsTableName string
+	snapshotsTableName        string
+	outboxTableName           string
+	eventsTableName           string
+	tableStrategy             TableStrategy
+	outbox                    bool
+	outboxDispatch            OutboxDispatch
+	globalOrdering            bool
+	maxPacketSize             int
+	gapDetection              *GapDetection
+	serializers               serializers
+	upcasters                 *Upcasters
+	unknownEvents             UnknownEventPolicy
+	typeRegistry              eventstore.TypeRegistry
+	prooph                    *Prooph
+}
+
+// UnknownEventPolicy defines how loading handles events without a registered type
+type UnknownEventPolicy int
+
+const (
+	// UnknownEventError stops loading with an UnknownEventType error
+	UnknownEventError UnknownEventPolicy = iota
+	// UnknownEventSkip ignores unknown events
+	UnknownEventSkip
+	// UnknownEventRaw returns unknown events with a RawEvent payload containing the undecoded name, payload and metadata
+	UnknownEventRaw
+)
+
+// GapDetection configures how long loading waits for missing event numbers of transactions which are not committed yet
+// A gap is confirmed by a locking read of the missing numbers, which waits until the inserting transactions end,
+// so gaps of rolled back transactions are skipped as soon as they are rolled back
+type GapDetection struct {
+	// Timeout is the maximum duration to wait for the transactions of a single gap, the default is 10s
+	// Loading stops before a gap whose transactions are still running afterwards, so no event is skipped
+	Timeout time.Duration
+	// Deprecated: gaps are confirmed by a locking read instead of polling
+	Interval time.Duration
+	// Deprecated: gaps are confirmed regardless of the client supplied creation time of the events
+	Window time.Duration
+}
+
+// WithDatabase qualifies all tables with the given database / schema name
+func WithDatabase(database string) Option {
+	return func(o *options) {
+		o.database = database
+	}
+}
+
+// WithTablePrefix replaces the default "_" prefix of the generated EventStream table names
+func WithTablePrefix(prefix string) Option {
+	return func(o *options) {
+		o.tablePrefix = prefix
+	}
+}
+
+// WithEventStreamsTable replaces the default EventStreamsTable name
+func WithEventStreamsTable(table string) Option {
+	return func(o *options) {
+		o.eventStreamsTableName = table
+	}
+}
+
+// WithProjectionsTable replaces the default ProjectionsTable name
+func WithProjectionsTable(table string) Option {
+	return func(o *options) {
+		o.projectionsTableName = table
+	}
+}
+
+// WithEventPositionsTable replaces the default EventPositionsTable name
+func WithEventPositionsTable(table string) Option {
+	return func(o *options) {
+		o.eventPositionsTableName = table
+	}
+}
+
+// WithSchemaMigrationsTable replaces the default SchemaMigrationsTable name used by the Migrator
+func WithSchemaMigrationsTable(table string) Option {
+	return func(o *options) {
+		o.schemaMigrationsTableName = table
+	}
+}
+
+// WithSnapshotsTable replaces the default SnapshotsTable name used by the SnapshotStore
+func WithSnapshotsTable(table string) Option {
+	return func(o *options) {
+		o.snapshotsTableName = table
+	}
+}
+
+// WithTableStrategy selects in which tables the events of the EventStreams are stored, the default is TablePerStream
+func WithTableStrategy(strategy TableStrategy) Option {
+	return func(o *options) {
+		o.tableStrategy = strategy
+	}
+}
+
+// WithEventsTable replaces the default EventsTable name of the SingleTable strategy
+func WithEventsTable(table string) Option {
+	return func(o *options) {
+		o.eventsTableName = table
+	}
+}
+
+// WithOutboxTable replaces the default OutboxTable name
+func WithOutboxTable(table string) Option {
+	return func(o *options) {
+		o.outboxTableName = table
+	}
+}
+
+// WithOutbox writes every appended event into the OutboxTable within the append transaction,
+// an OutboxDispatcher publishes the written events afterwards
+func WithOutbox() Option {
+	return func(o *options) {
+		o.outbox = true
+	}
+}
+
+// WithOutboxDispatch configures how an OutboxDispatcher claims and retries the written events
+func WithOutboxDispatch(dispatch OutboxDispatch) Option {
+	return func(o *options) {
+		if dispatch.BatchSize <= 0 {
+			dispatch.BatchSize = 100
+		}
+		if dispatch.Interval <= 0 {
+			dispatch.Interval = time.Second
+		}
+
+		o.outboxDispatch = dispatch
+	}
+}
+
+// WithMaxPacketSize limits the estimated size of a multi-row INSERT of AppendTo, it should stay below max_allowed_packet of the server
+// The default of 4MB matches the lowest default of max_allowed_packet
+func WithMaxPacketSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.maxPacketSize = size
+		}
+	}
+}
+
+// WithGlobalOrdering records a global position for every appended event in the EventPositionsTable
+// MergeAndLoad uses this position to return events of multiple streams in a deterministic order
+// Install backfills the positions of events without one, loads by position return MissingEventPositions while an event has no position
+// Appends assign their positions under a shared lock which is held until they commit, so positions become visible in ascending order
+func WithGlobalOrdering() Option {
+	return func(o *options) {
+		o.globalOrdering = true
+	}
+}
+
+// WithGapDetection lets Load and MergeAndLoad wait for gaps in the event numbers of unfiltered streams,
+// so projections do not skip events of concurrent transactions which commit a lower number later
+// Pages ordered by global position need no detection, their positions are committed in ascending order
+func WithGapDetection(gapDetection GapDetection) Option {
+	return func(o *options) {
+		if gapDetection.Timeout <= 0 {
+			gapDetection.Timeout = 10 * time.Second
+		}
+
+		o.gapDetection = &gapDetection
+	}
+}
+
+// StreamOption configures a new EventStream created by PersistenceStrategy.CreateStream
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	metadata map[string]interface{}
+	schema   StreamSchema
+}
+
+// WithSchemaKind selects the table layout of the new EventStream, the default is AggregateSchema
+func WithSchemaKind(kind SchemaKind) StreamOption {
+	return func(o *streamOptions) {
+		o.schema.Kind = kind
+	}
+}
+
+// WithIndexedMetadata materializes the given metadata fields of the new EventStream as indexed columns
+func WithIndexedMetadata(indexes ...MetadataIndex) StreamOption {
+	return func(o *streamOptions) {
+		o.schema.Indexes = append(o.schema.Indexes, indexes...)
+	}
+}
+
+// WithStreamMetadata stores the given metadata like owner or retention policy with the new EventStream
+func WithStreamMetadata(metadata map[string]interface{}) StreamOption {
+	return func(o *streamOptions) {
+		if metadata != nil {
+			o.metadata = metadata
+		}
+	}
+}
+
+// WithSerializer encodes appended event payloads with the given Serializer
+// The codec name is recorded per event, so events of other registered codecs remain readable
+func WithSerializer(serializer Serializer) Option {
+	return func(o *options) {
+		o.serializers.payload = serializer
+		o.serializers.register(serializer)
+	}
+}
+
+// WithSerializers registers additional Serializers to decode events written with other codecs
+func WithSerializers(serializers ...Serializer) Option {
+	return func(o *options) {
+		for _, serializer := range serializers {
+			o.serializers.register(serializer)
+		}
+	}
+}
+
+// WithMetadataSerializer encodes and decodes event metadata with the given Serializer, its output has to be JSON
+func WithMetadataSerializer(serializer Serializer) Option {
+	return func(o *options) {
+		o.serializers.metadata = serializer
+	}
+}
+
+// WithUpcasters transforms loaded events of outdated schema versions before they are decoded
+func WithUpcasters(upcasters *Upcasters) Option {
+	return func(o *options) {
+		o.upcasters = upcasters
+	}
+}
+
+// WithUnknownEventPolicy defines how loading handles events without a registered type, the default is UnknownEventError
+func WithUnknownEventPolicy(policy UnknownEventPolicy) Option {
+	return func(o *options) {
+		o.unknownEvents = policy
+	}
+}
+
+// WithProophCompatibility reads and writes databases created by the prooph pdo-event-store
+func WithProophCompatibility(prooph Prooph) Option {
+	return func(o *options) {
+		o.prooph = &prooph
+	}
+}
+
+// WithTypeRegistry decodes loaded events with the types of the given registry instead of the global eventstore.NewTypeRegistry
+func WithTypeRegistry(registry eventstore.TypeRegistry) Option {
+	return func(o *options) {
+		o.typeRegistry = registry
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		tablePrefix:               defaultTablePrefix,
+		eventStreamsTableName:     EventStreamsTable,
+		projectionsTableName:      ProjectionsTable,
+		eventPositionsTableName:   EventPositionsTable,
+		schemaMigrationsTableName: SchemaMigrationsTable,
+		snapshotsTableName:        SnapshotsTable,
+		outboxTableName:           OutboxTable,
+		eventsTableName:           EventsTable,
+		outboxDispatch:            OutboxDispatch{BatchSize: 100, Interval: time.Second},
+		maxPacketSize:             4 << 20,
+		serializers:               newSerializers(),
+		typeRegistry:              eventstore.NewTypeRegistry(),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func (o options) qualify(table string) string {
+	if o.database == "" {
+		return "`" + table + "`"
+	}
+
+	return "`" + o.database + "`.`" + table + "`"
+}
+
+func (o options) streamsTable() string {
+	return o.qualify(o.eventStreamsTableName)
+}
+
+func (o options) projectionsTable() string {
+	return o.qualify(o.projectionsTableName)
+}
+
+func (o options) positionsTable() string {
+	return o.qualify(o.eventPositionsTableName)
+}
+
+func (o options) snapshotsTable() string {
+	return o.qualify(o.snapshotsTableName)
+}
+
+func (o options) eventsTable() string {
+	return o.qualify(o.eventsTableName)
+}
+
+func (o options) outboxTable() string {
+	return o.qualify(o.outboxTableName)
+}
+
+func (o options) generateTableName(streamName string) string {
+	return generateTableName(o.tablePrefix, streamName)
+}
+
+// tableNameLength is the length of the generated EventStream table names, a prefix followed by a sha1 hex hash
+func (o options) tableNameLength() int {
+	return len(o.tablePrefix) + 40
+}
+
+func newStreamOptions(opts []StreamOption) streamOptions {
+	o := streamOptions{metadata: map[string]interface{}{}, schema: StreamSchema{Kind: AggregateSchema}}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,239 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+const OutboxTable = "outbox"
+
+// OutboxDispatch configures how an OutboxDispatcher claims and retries the written events
+type OutboxDispatch struct {
+	// BatchSize is the maximum number of messages claimed per transaction
+	BatchSize int
+	// Interval between two dispatches if no message was delivered
+	Interval time.Duration
+	// MaxAttempts stops retrying a failing message after the given number of attempts, zero retries forever
+	MaxAttempts int
+}
+
+// OutboxMessage is an appended event written to the OutboxTable with its encoded payload and metadata
+type OutboxMessage struct {
+	ID        int
+	Stream    string
+	Number    int
+	EventID   string
+	EventName string
+	Payload   []byte
+	Metadata  []byte
+	CreatedAt time.Time
+	Attempts  int
+}
+
+// Publisher delivers OutboxMessages to a message broker, a returned error marks the message for another attempt
+type Publisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error
+}
+
+// PublisherFunc is a function implementing the Publisher interface
+type PublisherFunc func(ctx context.Context, message OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, message OutboxMessage) error {
+	return f(ctx, message)
+}
+
+func (ps PersistenceStrategy) CreateOutboxTable(ctx context.Context) error {
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ps.options.outboxTable())).Err()
+	if err == nil {
+		return ps.migrateOutboxKey(ctx)
+	}
+
+	_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+            id BIGINT(20) NOT NULL AUTO_INCREMENT,
+            stream_name VARCHAR(150) NOT NULL,
+            event_no BIGINT(20) NOT NULL,
+            event_id CHAR(36) NOT NULL,
+            event_name VARCHAR(100) NOT NULL,
+            payload JSON NOT NULL,
+            metadata JSON NOT NULL,
+            created_at DATETIME(6) NOT NULL,
+            attempts INT(11) UNSIGNED NOT NULL DEFAULT 0,
+            last_error TEXT,
+            delivered_at DATETIME(6),
+            PRIMARY KEY (id),
+            UNIQUE KEY ix_event_id (stream_name, event_id),
+            KEY ix_pending (delivered_at, id)
+		  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, ps.options.outboxTable()))
+
+	return err
+}
+
+// migrateOutboxKey scopes the event_id key of OutboxTables created before to the stream, shared tables only keep event ids unique per stream
+func (ps PersistenceStrategy) migrateOutboxKey(ctx context.Context) error {
+	var columns int
+
+	err := ps.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND INDEX_NAME = 'ix_event_id'`,
+		ps.options.database,
+		ps.options.outboxTableName,
+	).Scan(&columns)
+	if err != nil || columns != 1 {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DROP INDEX ix_event_id, ADD UNIQUE KEY ix_event_id (stream_name, event_id)`, ps.options.outboxTable()))
+
+	return err
+}
+
+// appendOutbox copies the appended events with their numbers into the OutboxTable
+func (ps PersistenceStrategy) appendOutbox(ctx context.Context, conn execQuerier, streamName string, table streamTable, events []eventstore.DomainEvent) error {
+	placeholder := make([]string, 0, len(events))
+	parameters := make([]interface{}, 0, len(events))
+
+	for _, ev := range events {
+		placeholder = append(placeholder, "?")
+		parameters = append(parameters, ev.UUID().String())
+	}
+
+	where, parameters := table.where([]string{fmt.Sprintf(`event_id IN (%s)`, strings.Join(placeholder, ","))}, parameters...)
+
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO %s (stream_name, event_no, event_id, event_name, payload, metadata, created_at) SELECT ?, no, event_id, event_name, payload, metadata, created_at FROM %s WHERE %s ORDER BY no ASC`,
+			ps.options.outboxTable(),
+			table.name,
+			where,
+		),
+		append([]interface{}{streamName}, parameters...)...,
+	)
+
+	return err
+}
+
+// OutboxDispatcher publishes the messages of the OutboxTable and records their attempts, failures and delivery time
+// Messages are claimed with SKIP LOCKED, so multiple dispatchers can run concurrently without publishing a message twice at the same time
+type OutboxDispatcher struct {
+	db        *sql.DB
+	options   options
+	publisher Publisher
+}
+
+// Dispatch claims the next batch of undelivered messages and publishes them in the order of their ids
+// It returns the number of delivered messages, a failed message does not stop the batch
+func (d OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	messages, err := d.claim(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+
+	for _, message := range messages {
+		err = d.publisher.Publish(ctx, message)
+		if err != nil {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = ? WHERE id = ?`, d.options.outboxTable()), err.Error(), message.ID)
+			if err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = NULL, delivered_at = UTC_TIMESTAMP(6) WHERE id = ?`, d.options.outboxTable()), message.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		delivered++
+	}
+
+	return delivered, tx.Commit()
+}
+
+func (d OutboxDispatcher) claim(ctx context.Context, tx *sql.Tx) ([]OutboxMessage, error) {
+	wheres := []string{`delivered_at IS NULL`}
+	parameters := []interface{}{}
+
+	if d.options.outboxDispatch.MaxAttempts > 0 {
+		wheres = append(wheres, `attempts < ?`)
+		parameters = append(parameters, d.options.outboxDispatch.MaxAttempts)
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, stream_name, event_no, event_id, event_name, payload, metadata, created_at, attempts FROM %s WHERE %s ORDER BY id ASC LIMIT %d FOR UPDATE SKIP LOCKED`,
+		d.options.outboxTable(),
+		strings.Join(wheres, " AND "),
+		d.options.outboxDispatch.BatchSize,
+	), parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []OutboxMessage{}
+
+	for rows.Next() {
+		var message OutboxMessage
+
+		err = rows.Scan(
+			&message.ID,
+			&message.Stream,
+			&message.Number,
+			&message.EventID,
+			&message.EventName,
+			&message.Payload,
+			&message.Metadata,
+			&message.CreatedAt,
+			&message.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// Run dispatches until the context is cancelled, it waits for the configured interval whenever no message was delivered
+func (d OutboxDispatcher) Run(ctx context.Context) error {
+	for {
+		delivered, err := d.Dispatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if delivered > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.options.outboxDispatch.Interval):
+		}
+	}
+}
+
+func NewOutboxDispatcher(db *sql.DB, publisher Publisher, opts ...Option) OutboxDispatcher {
+	return OutboxDispatcher{db: db, options: newOptions(opts), publisher: publisher}
+}
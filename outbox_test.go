@@ -0,0 +1,190 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+type memoryPublisher struct {
+	mutex    sync.Mutex
+	failures int
+	messages []mysql.OutboxMessage
+}
+
+func (p *memoryPublisher) Publish(ctx context.Context, message mysql.OutboxMessage) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+
+	p.messages = append(p.messages, message)
+
+	return nil
+}
+
+func Test_MysqlOutbox(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type TestEvent struct {
+		Foo string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(TestEvent{})
+
+	eventStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db, mysql.WithOutbox()))
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("Dispatch appended events", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "DELETE FROM outbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "foo"}, nil, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "bar"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		publisher := &memoryPublisher{failures: 1}
+		dispatcher := mysql.NewOutboxDispatcher(db, publisher)
+
+		delivered, err := dispatcher.Dispatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delivered != 1 {
+			t.Fatalf("Expected 1 delivered message besides the failed one, got %d", delivered)
+		}
+
+		var attempts int
+		var lastError string
+
+		err = db.QueryRowContext(ctx, "SELECT attempts, last_error FROM outbox WHERE delivered_at IS NULL").Scan(&attempts, &lastError)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != 1 || lastError != "broker unavailable" {
+			t.Errorf("Expected recorded failure, got %d attempts with error %s", attempts, lastError)
+		}
+
+		delivered, err = dispatcher.Dispatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delivered != 1 || len(publisher.messages) != 2 {
+			t.Fatalf("Expected retried message, got %d delivered and %d published", delivered, len(publisher.messages))
+		}
+
+		if publisher.messages[0].Stream != "foo-stream" || publisher.messages[0].EventName != "TestEvent" {
+			t.Errorf("Unexpected message %v", publisher.messages[0])
+		}
+
+		delivered, err = dispatcher.Dispatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if delivered != 0 {
+			t.Errorf("Expected no pending messages, got %d delivered", delivered)
+		}
+	})
+
+	t.Run("No outbox message for a failed append", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "DELETE FROM outbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		event := eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "foo"}, nil, time.Now())
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{event, event})
+		if err == nil {
+			t.Fatal("Expected a failing append")
+		}
+
+		var count int
+
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("Expected no outbox messages, got %d", count)
+		}
+	})
+	t.Run("Same event id in two streams of a shared table", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "DELETE FROM outbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sharedStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db, mysql.WithOutbox(), mysql.WithTableStrategy(mysql.SingleTable)))
+		err = sharedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event := eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "foo"}, nil, time.Now())
+
+		for _, streamName := range []string{"foo-stream", "bar-stream"} {
+			err = sharedStore.CreateStream(ctx, streamName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sharedStore.DeleteStream(ctx, streamName)
+
+			err = sharedStore.AppendTo(ctx, streamName, []eventstore.DomainEvent{event})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var count int
+
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM outbox").Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 outbox messages, got %d", count)
+		}
+	})
+}
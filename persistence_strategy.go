@@ -16,48 +16,252 @@ import (
 )
 
 const (
-	EventStreamsTable = "event_streams"
-	ProjectionsTable  = "projections"
+	EventStreamsTable   = "event_streams"
+	ProjectionsTable    = "projections"
+	EventPositionsTable = "event_positions"
 )
 
+// AnyVersion disables the expected version check of AppendToWithExpectedVersion
+const AnyVersion = -1
+
+// aggregateColumns are the generated columns of the aggregate metadata in every EventStream table
+var aggregateColumns = map[string]string{
+	"_aggregate_type":    "aggregate_type",
+	"_aggregate_id":      "aggregate_id",
+	"_aggregate_version": "aggregate_version",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type PersistenceStrategy struct {
-	db *sql.DB
+	db      *sql.DB
+	tx      *sql.Tx
+	options options
+}
+
+// WithTx returns a copy of the PersistenceStrategy appending events within the given transaction controlled by the caller
+// Loading and schema changes keep using their own connections
+func (ps PersistenceStrategy) WithTx(tx *sql.Tx) *PersistenceStrategy {
+	ps.tx = tx
+
+	return &ps
 }
 
 func GenerateTableName(streamName string) string {
+	return generateTableName(defaultTablePrefix, streamName)
+}
+
+func generateTableName(prefix, streamName string) string {
 	h := sha1.New()
 	h.Write([]byte(streamName))
 
-	return "_" + hex.EncodeToString(h.Sum(nil))
+	return prefix + hex.EncodeToString(h.Sum(nil))
 }
 
 func (ps PersistenceStrategy) CreateEventStreamsTable(ctx context.Context) error {
-	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, EventStreamsTable)).Err()
-	if err == nil {
+	err := ps.createEventStreamsTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the optional tables check their own existence, so options enabled for an installed EventStore take effect on Install
+	if ps.options.tableStrategy == SingleTable {
+		err = ps.CreateEventsTable(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ps.options.globalOrdering {
+		err = ps.CreateEventPositionsTable(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ps.options.outbox {
+		return ps.CreateOutboxTable(ctx)
+	}
+
+	return nil
+}
+
+func (ps PersistenceStrategy) createEventStreamsTable(ctx context.Context) error {
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ps.options.streamsTable())).Err()
+	if err == nil && ps.options.prooph != nil {
 		return nil
 	}
+	if err == nil {
+		return ps.addStreamSchemaColumn(ctx)
+	}
+
+	if ps.options.prooph != nil {
+		return ps.createProophEventStreamsTable(ctx)
+	}
 
 	_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`
 		CREATE TABLE %s (
             no BIGINT(20) NOT NULL AUTO_INCREMENT,
             real_stream_name VARCHAR(150) NOT NULL,
-            stream_name CHAR(41) NOT NULL,
+            stream_name CHAR(%d) NOT NULL,
             metadata JSON,
+            stream_schema JSON,
             PRIMARY KEY (no),
             UNIQUE KEY ix_rsn (real_stream_name)
+		  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`,
+		ps.options.streamsTable(), ps.options.tableNameLength()))
+
+	return err
+}
+
+func (ps PersistenceStrategy) CreateEventPositionsTable(ctx context.Context) error {
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ps.options.positionsTable())).Err()
+	if err != nil {
+		_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+            position BIGINT(20) NOT NULL AUTO_INCREMENT,
+            event_table CHAR(%d) NOT NULL,
+            event_no BIGINT(20) NOT NULL,
+            PRIMARY KEY (position),
+            UNIQUE KEY ix_event (event_table, event_no),
+            KEY ix_table_position (event_table, position)
 		  ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;`,
-		EventStreamsTable))
+			ps.options.positionsTable(), ps.options.tableNameLength()))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the lock row is added after the first complete backfill, until then appends with global ordering fail
+	err = ps.backfillEventPositions(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ps.createPositionsLock(ctx, ps.db)
+}
+
+// createPositionsLock inserts the row of the EventPositionsTable which is locked by appendPositions, it belongs to no EventStream
+func (ps PersistenceStrategy) createPositionsLock(ctx context.Context, conn execQuerier) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`INSERT IGNORE INTO %s (event_table, event_no) VALUES ('', 0)`, ps.options.positionsTable()))
 
 	return err
 }
 
+// lockPositions locks the lock row of the EventPositionsTable until the transaction commits,
+// so concurrent appends assign and commit their positions in the same order and readers never pass an uncommitted position
+func (ps PersistenceStrategy) lockPositions(ctx context.Context, conn execQuerier) error {
+	var position int
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT position FROM %s WHERE event_table = '' AND event_no = 0 FOR UPDATE`, ps.options.positionsTable())).Scan(&position)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("The positions of existing events are not backfilled yet, Install has to complete before appending with global ordering")
+	}
+
+	return err
+}
+
+// backfillEventPositions assigns positions to the events without one, stream by stream in the order of their numbers
+func (ps PersistenceStrategy) backfillEventPositions(ctx context.Context) error {
+	rows, err := ps.db.QueryContext(ctx, fmt.Sprintf(`SELECT real_stream_name FROM %s ORDER BY no ASC`, ps.options.streamsTable()))
+	if err != nil {
+		return err
+	}
+
+	streams := []string{}
+
+	for rows.Next() {
+		var streamName string
+
+		err = rows.Scan(&streamName)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		// the internal EventStreams of the AggregateTypeTable strategy only register tables
+		if ps.options.tableStrategy == AggregateTypeTable && strings.HasPrefix(streamName, aggregateTablePrefix) {
+			continue
+		}
+
+		streams = append(streams, streamName)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// each table is backfilled by its own statement, events which already have a position are skipped, so an interrupted backfill is resumed by the next Install
+	for _, streamName := range streams {
+		tables, err := ps.streamTables(ctx, ps.db, streamName)
+		if err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			err = ps.backfillTablePositions(ctx, table)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// backfillTablePositions assigns positions to the events of the table without one, under the lock of concurrent appends
+func (ps PersistenceStrategy) backfillTablePositions(ctx context.Context, table streamTable) error {
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+
+	// the lock row does not exist before the first backfill completed
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT position FROM %s WHERE event_table = '' AND event_no = 0 FOR UPDATE`, ps.options.positionsTable())).Scan(&position)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	where, values := table.where(nil)
+
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`INSERT IGNORE INTO %[1]s (event_table, event_no) SELECT ?, no FROM %[2]s LEFT JOIN %[1]s p ON p.event_table = ? AND p.event_no = no WHERE %[3]s AND p.position IS NULL ORDER BY no ASC`,
+			ps.options.positionsTable(),
+			table.name,
+			where,
+		),
+		append([]interface{}{table.key, table.key}, values...)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (ps PersistenceStrategy) CreateProjectionsTable(ctx context.Context) error {
-	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ProjectionsTable)).Err()
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ps.options.projectionsTable())).Err()
 
 	if err == nil {
 		return nil
 	}
 
+	if ps.options.prooph != nil {
+		return ps.createProophProjectionsTable(ctx)
+	}
+
 	_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`
 		CREATE TABLE %s (
             no BIGINT(20) NOT NULL AUTO_INCREMENT,
@@ -66,29 +270,146 @@ func (ps PersistenceStrategy) CreateProjectionsTable(ctx context.Context) error
             state JSON,
             status VARCHAR(28) NOT NULL,
             locked_until CHAR(26),
+            locked_by VARCHAR(150),
             PRIMARY KEY (no),
             UNIQUE KEY ix_name (name)
-          ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;`, ProjectionsTable))
+          ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, ps.options.projectionsTable()))
 
 	return err
 }
 
 func (ps PersistenceStrategy) AddStreamToStreamsTable(ctx context.Context, streamName string) error {
-	tableName := GenerateTableName(streamName)
-	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (real_stream_name, stream_name, metadata) VALUES (?, ?, ?)`, EventStreamsTable))
+	return ps.AddStreamToStreamsTableWithMetadata(ctx, streamName, map[string]interface{}{})
+}
+
+func (ps PersistenceStrategy) AddStreamToStreamsTableWithMetadata(ctx context.Context, streamName string, metadata map[string]interface{}) error {
+	return ps.addStream(ctx, streamName, metadata, StreamSchema{Kind: AggregateSchema})
+}
+
+func (ps PersistenceStrategy) addStream(ctx context.Context, streamName string, metadata map[string]interface{}, schema StreamSchema) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	tableName := ps.options.generateTableName(streamName)
+	columns := `real_stream_name, stream_name, metadata`
+	values := []interface{}{streamName, tableName, data}
+
+	if ps.options.prooph != nil {
+		columns += `, category`
+		values = append(values, proophCategory(streamName))
+	} else {
+		encoded, err := json.Marshal(schema)
+		if err != nil {
+			return err
+		}
+
+		columns += `, stream_schema`
+		values = append(values, encoded)
+	}
+
+	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (?%s)`, ps.options.streamsTable(), columns, strings.Repeat(", ?", len(values)-1)))
+	// EventStreams tables without the stream_schema column of migration 3 can only contain streams with the default AggregateSchema
+	legacy := isUnknownColumn(err) && ps.options.prooph == nil
+	if legacy && schema.Kind == AggregateSchema && len(schema.Indexes) == 0 {
+		values = values[:3]
+		stmt, err = ps.db.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (real_stream_name, stream_name, metadata) VALUES (?, ?, ?)`, ps.options.streamsTable()))
+	}
+	if legacy && err != nil {
+		return fmt.Errorf("Schema of stream %s cannot be recorded before migration 3 is applied: %s", streamName, err.Error())
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(streamName, tableName, "{}")
+	_, err = stmt.ExecContext(ctx, values...)
+	if isDuplicateKey(err, "ix_rsn") {
+		return eventstore.StreamAlreadyExist{Stream: streamName}
+	}
 
-	// @TODO check unique error
 	return err
 }
 
+// CreateStream adds the EventStream to the EventStreams Table and creates its Schema
+func (ps PersistenceStrategy) CreateStream(ctx context.Context, streamName string, opts ...StreamOption) error {
+	o := newStreamOptions(opts)
+
+	err := o.schema.validate(ps.options)
+	if err != nil {
+		return err
+	}
+
+	err = ps.addStream(ctx, streamName, o.metadata, o.schema)
+	if err != nil {
+		return err
+	}
+
+	err = ps.createSchema(ctx, streamName, o.schema)
+	if err != nil {
+		ps.RemoveStreamFromStreamsTable(ctx, streamName)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateStreamMetadata replaces the metadata of the given EventStream
+func (ps PersistenceStrategy) UpdateStreamMetadata(ctx context.Context, streamName string, metadata map[string]interface{}) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	r, err := ps.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET metadata = ? WHERE real_stream_name = ?`, ps.options.streamsTable()), data, streamName)
+	if err != nil {
+		return err
+	}
+
+	count, err := r.RowsAffected()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	exists, err := ps.HasStream(ctx, streamName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return eventstore.StreamNotFound{Stream: streamName}
+	}
+
+	return nil
+}
+
+// FetchStreamMetadata returns the metadata of the given EventStream
+func (ps PersistenceStrategy) FetchStreamMetadata(ctx context.Context, streamName string) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+
+	var data []byte
+
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT metadata FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()), streamName).Scan(&data)
+	if err == sql.ErrNoRows {
+		return metadata, eventstore.StreamNotFound{Stream: streamName}
+	}
+	if err != nil {
+		return metadata, err
+	}
+
+	if ps.options.prooph != nil {
+		data = proophObject(data)
+	}
+
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &metadata)
+	}
+
+	return metadata, err
+}
+
 func (ps PersistenceStrategy) RemoveStreamFromStreamsTable(ctx context.Context, streamName string) error {
-	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE real_stream_name = ?`, EventStreamsTable))
+	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -109,7 +430,7 @@ func (ps PersistenceStrategy) RemoveStreamFromStreamsTable(ctx context.Context,
 func (ps PersistenceStrategy) FetchAllStreamNames(ctx context.Context) ([]string, error) {
 	streams := []string{}
 
-	rows, err := ps.db.QueryContext(ctx, fmt.Sprintf(`SELECT real_stream_name FROM %s WHERE real_stream_name NOT LIKE '$%%'`, EventStreamsTable))
+	rows, err := ps.db.QueryContext(ctx, fmt.Sprintf(`SELECT real_stream_name FROM %s WHERE real_stream_name NOT LIKE '$%%'`, ps.options.streamsTable()))
 	if err != nil {
 		return streams, err
 	}
@@ -128,8 +449,126 @@ func (ps PersistenceStrategy) FetchAllStreamNames(ctx context.Context) ([]string
 	return streams, nil
 }
 
+// StreamFilter restricts and pages the result of ListStreams
+type StreamFilter struct {
+	// Name matches the exact stream name
+	Name string
+	// Prefix matches all stream names starting with the given value
+	Prefix string
+	// Regex matches all stream names with the given regular expression
+	Regex string
+	// Metadata filters on the stream metadata, only eventstore.MetadataField matches are supported
+	Metadata eventstore.MetadataMatcher
+	// IncludeInternal includes internal streams prefixed with $
+	IncludeInternal bool
+	// After is a cursor which skips all streams up to the given creation number
+	After int
+	// Offset skips the given number of streams
+	Offset int
+	// Limit the number of returned streams, 0 means unlimited
+	Limit int
+}
+
+// StreamInfo describes an existing EventStream
+type StreamInfo struct {
+	// No is the creation number of the stream
+	No        int
+	Name      string
+	TableName string
+	Metadata  map[string]interface{}
+	Schema    StreamSchema
+}
+
+// ListStreams returns all EventStreams matching the filter in their creation order
+func (ps PersistenceStrategy) ListStreams(ctx context.Context, filter StreamFilter) ([]StreamInfo, error) {
+	streams := []StreamInfo{}
+
+	for _, match := range filter.Metadata {
+		if match.FieldType != eventstore.MetadataField {
+			return streams, fmt.Errorf("Unsupported field type %s to filter streams", match.FieldType)
+		}
+	}
+
+	wheres, values, err := ps.createWhereClause(filter.Metadata, nil)
+	if err != nil {
+		return streams, err
+	}
+
+	if !filter.IncludeInternal {
+		wheres = append(wheres, `real_stream_name NOT LIKE '$%'`)
+	}
+	if filter.Name != "" {
+		wheres = append(wheres, `real_stream_name = ?`)
+		values = append(values, filter.Name)
+	}
+	if filter.Prefix != "" {
+		wheres = append(wheres, `real_stream_name LIKE ?`)
+		values = append(values, likeEscaper.Replace(filter.Prefix)+"%")
+	}
+	if filter.Regex != "" {
+		wheres = append(wheres, `real_stream_name REGEXP ?`)
+		values = append(values, filter.Regex)
+	}
+
+	wheres = append(wheres, `no > ?`)
+	values = append(values, filter.After)
+
+	query := func(schemaColumn string) string {
+		query := fmt.Sprintf(`SELECT no, real_stream_name, stream_name, metadata, %s FROM %s WHERE %s ORDER BY no ASC`, schemaColumn, ps.options.streamsTable(), strings.Join(wheres, " AND "))
+
+		if filter.Limit > 0 {
+			return fmt.Sprintf(`%s LIMIT %d OFFSET %d`, query, filter.Limit, filter.Offset)
+		} else if filter.Offset > 0 {
+			return fmt.Sprintf(`%s LIMIT 18446744073709551615 OFFSET %d`, query, filter.Offset)
+		}
+
+		return query
+	}
+
+	rows, err := ps.db.QueryContext(ctx, query(ps.options.streamSchemaColumn()), values...)
+	// EventStreams tables without the stream_schema column of migration 3 only contain streams with the AggregateSchema
+	if isUnknownColumn(err) {
+		rows, err = ps.db.QueryContext(ctx, query(`NULL`), values...)
+	}
+	if err != nil {
+		return streams, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stream StreamInfo
+		var metadata, schema []byte
+
+		err = rows.Scan(&stream.No, &stream.Name, &stream.TableName, &metadata, &schema)
+		if err != nil {
+			return []StreamInfo{}, err
+		}
+
+		stream.Schema, err = decodeStreamSchema(schema)
+		if err != nil {
+			return []StreamInfo{}, err
+		}
+
+		if ps.options.prooph != nil {
+			metadata = proophObject(metadata)
+		}
+
+		stream.Metadata = map[string]interface{}{}
+		if len(metadata) > 0 {
+			err = json.Unmarshal(metadata, &stream.Metadata)
+			if err != nil {
+				return []StreamInfo{}, err
+			}
+		}
+
+		streams = append(streams, stream)
+	}
+
+	return streams, rows.Err()
+}
+
 func (ps PersistenceStrategy) HasStream(ctx context.Context, streamName string) (bool, error) {
-	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`SELECT COUNT(real_stream_name) FROM %s WHERE real_stream_name = ?`, EventStreamsTable))
+	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`SELECT COUNT(real_stream_name) FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()))
 	if err != nil {
 		return false, err
 	}
@@ -158,8 +597,35 @@ func (ps PersistenceStrategy) DeleteStream(ctx context.Context, streamName strin
 	return ps.DropSchema(ctx, streamName)
 }
 
+// CreateSchema creates the table of the EventStream, the shared tables of the other strategies exist already or are created on append
 func (ps PersistenceStrategy) CreateSchema(ctx context.Context, streamName string) error {
-	tableName := GenerateTableName(streamName)
+	return ps.createSchema(ctx, streamName, StreamSchema{Kind: AggregateSchema})
+}
+
+func (ps PersistenceStrategy) createSchema(ctx context.Context, streamName string, schema StreamSchema) error {
+	if ps.options.tableStrategy != TablePerStream {
+		return nil
+	}
+
+	table := ps.streamTable(streamName)
+	columns, keys := schema.indexDefinitions()
+
+	if schema.Kind == SimpleSchema {
+		_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`
+			CREATE TABLE %s (
+				no BIGINT(20) NOT NULL AUTO_INCREMENT,
+				event_id CHAR(36) COLLATE utf8mb4_bin NOT NULL,
+				event_name VARCHAR(100) COLLATE utf8mb4_bin NOT NULL,
+				payload JSON NOT NULL,
+				metadata JSON NOT NULL,
+				created_at DATETIME(6) NOT NULL,
+				%sPRIMARY KEY (no),
+				UNIQUE KEY ix_event_id (event_id)%s
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, table.name, columns, keys))
+
+		return err
+	}
+
 	_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`
 		CREATE TABLE %s (
 			no BIGINT(20) NOT NULL AUTO_INCREMENT,
@@ -168,30 +634,61 @@ func (ps PersistenceStrategy) CreateSchema(ctx context.Context, streamName strin
 			payload JSON NOT NULL,
 			metadata JSON NOT NULL,
 			created_at DATETIME(6) NOT NULL,
-			aggregate_version INT(11) UNSIGNED GENERATED ALWAYS AS (JSON_EXTRACT(metadata, '$._aggregate_version')) STORED NOT NULL,
-			aggregate_id CHAR(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(metadata, '$._aggregate_id'))) STORED NOT NULL,
-			aggregate_type VARCHAR(150) GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(metadata, '$._aggregate_type'))) STORED NOT NULL,
-			PRIMARY KEY (no),
+			%s
+			%sPRIMARY KEY (no),
 			UNIQUE KEY ix_event_id (event_id),
 			UNIQUE KEY ix_unique_event (aggregate_type, aggregate_id, aggregate_version),
-			KEY ix_query_aggregate (aggregate_type,aggregate_id,no)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, tableName))
+			KEY ix_query_aggregate (aggregate_type,aggregate_id,no)%s
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, table.name, aggregateColumnsDefinition, columns, keys))
 
 	return err
 }
 
+// DropSchema drops the table of the EventStream, from shared tables only its rows are deleted
 func (ps PersistenceStrategy) DropSchema(ctx context.Context, streamName string) error {
-	tableName := GenerateTableName(streamName)
-	_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, tableName))
+	tables, err := ps.streamTables(ctx, ps.db, streamName)
 	if err != nil {
 		return err
 	}
 
+	for _, table := range tables {
+		if table.shared {
+			_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE stream_name = ?`, table.name), table.key)
+		} else {
+			_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, table.name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if ps.options.globalOrdering {
+		_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE event_table = ?`, ps.options.positionsTable()), ps.options.generateTableName(streamName))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (ps PersistenceStrategy) AppendTo(ctx context.Context, streamName string, events []eventstore.DomainEvent) error {
-	tableName := GenerateTableName(streamName)
+	return ps.AppendToWithExpectedVersion(ctx, streamName, AnyVersion, events)
+}
+
+// AppendToWithExpectedVersion appends the events only if the current version of their aggregate matches the expectedVersion
+// All events have to belong to the same aggregate, use AnyVersion to skip the version check
+func (ps PersistenceStrategy) AppendToWithExpectedVersion(ctx context.Context, streamName string, expectedVersion int, events []eventstore.DomainEvent) error {
+	err := ps.createAggregateTables(ctx, events)
+	if err != nil {
+		return err
+	}
+
+	if ps.tx != nil {
+		return inSavepoint(ctx, ps.tx, func() error {
+			return ps.appendInTx(ctx, ps.tx, streamName, expectedVersion, events)
+		})
+	}
 
 	tx, err := ps.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -199,107 +696,545 @@ func (ps PersistenceStrategy) AppendTo(ctx context.Context, streamName string, e
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (event_id, event_name, payload, metadata, created_at) VALUES (?, ?, ?, ?, ?)`, tableName))
+	err = ps.appendInTx(ctx, tx, streamName, expectedVersion, events)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	for _, ev := range events {
-		payload, err := json.Marshal(ev.Payload())
+	return tx.Commit()
+}
+
+// appendInTx appends the events within the given transaction, the caller is responsible to commit or roll it back
+func (ps PersistenceStrategy) appendInTx(ctx context.Context, tx *sql.Tx, streamName string, expectedVersion int, events []eventstore.DomainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	schema, err := ps.fetchStreamSchema(ctx, tx, streamName)
+	if err != nil {
+		return err
+	}
+
+	if expectedVersion != AnyVersion && schema.Kind == SimpleSchema {
+		return fmt.Errorf("Expected version check requires the aggregate columns missing in the %s schema of stream %s", schema.Kind, streamName)
+	}
+
+	if expectedVersion != AnyVersion {
+		aggregateType, aggregateID, err := aggregateOf(events)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 
-		metadata, err := json.Marshal(ev.Metadata())
+		version, err := ps.aggregateVersion(ctx, tx, ps.appendTable(streamName, events[0]), aggregateType, aggregateID)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 
-		_, err = stmt.ExecContext(
-			ctx,
-			ev.UUID().String(),
-			ev.Name(),
-			payload,
-			metadata,
-			ev.CreatedAt(),
-		)
+		if version != expectedVersion {
+			return ConcurrencyError{
+				Stream:          streamName,
+				AggregateID:     aggregateID,
+				ExpectedVersion: expectedVersion,
+				ActualVersion:   version,
+			}
+		}
+	}
+
+	rows, err := ps.encodeEvents(events)
+	if err != nil {
+		return err
+	}
+
+	if ps.options.tableStrategy != TablePerStream {
+		tables, err := ps.streamTables(ctx, tx, streamName)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
+
+		last, err := ps.lastNumber(ctx, tx, streamName, tables)
+		if err != nil {
+			return err
+		}
+
+		for i := range rows {
+			rows[i].number = last + i + 1
+		}
 	}
 
-	return tx.Commit()
+	for _, group := range ps.groupRows(streamName, rows) {
+		for _, chunk := range ps.chunkRows(group.rows) {
+			err = ps.insertRows(ctx, tx, group.table, chunk)
+			if schema.Kind != SimpleSchema && isDuplicateKey(err, "ix_unique_event", "ix_event_id") {
+				// MySQL only rolls back the failed statement, inserting the chunk row by row identifies the conflicting event
+				err = ps.insertEach(ctx, tx, streamName, group.table, expectedVersion, chunk)
+			}
+			if err != nil {
+				return err
+			}
+
+			err = ps.appendChunk(ctx, tx, streamName, group.table, chunk)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
-func (ps PersistenceStrategy) Load(ctx context.Context, streamName string, fromNumber, count int, matcher eventstore.MetadataMatcher) (eventstore.DomainEventIterator, error) {
-	query, values, err := ps.createQuery(ctx, streamName, fromNumber, matcher)
-	if err != nil {
-		return nil, err
+// rowGroup are consecutive rows of an append stored in the same table
+type rowGroup struct {
+	table streamTable
+	rows  []appendRow
+}
+
+// groupRows splits the rows into consecutive groups by their table, so the global positions keep the order of the events
+func (ps PersistenceStrategy) groupRows(streamName string, rows []appendRow) []rowGroup {
+	groups := []rowGroup{}
+	start := 0
+
+	for i := range rows {
+		table := ps.appendTable(streamName, rows[i].event)
+
+		if i > start && table != groups[len(groups)-1].table {
+			start = i
+		}
+
+		if i == start {
+			groups = append(groups, rowGroup{table: table})
+		}
+
+		groups[len(groups)-1].rows = rows[start : i+1]
 	}
 
-	return NewDomainEventIterator(ctx, ps.db, query, values, count), nil
+	return groups
 }
 
-func (ps PersistenceStrategy) MergeAndLoad(ctx context.Context, count int, streams ...eventstore.LoadStreamParameter) (eventstore.DomainEventIterator, error) {
-	var queries []string
-	var parameters []interface{}
+// appendChunk records the global positions and outbox messages of an inserted chunk
+func (ps PersistenceStrategy) appendChunk(ctx context.Context, conn execQuerier, streamName string, table streamTable, rows []appendRow) error {
+	events := make([]eventstore.DomainEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, row.event)
+	}
 
-	for _, stream := range streams {
-		query, values, err := ps.createQuery(ctx, stream.StreamName, stream.FromNumber, stream.Matcher)
+	if ps.options.globalOrdering {
+		err := ps.appendPositions(ctx, conn, table, events)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ps.options.outbox {
+		return ps.appendOutbox(ctx, conn, streamName, table, events)
+	}
+
+	return nil
+}
+
+// appendRow is an encoded event of an append
+type appendRow struct {
+	event eventstore.DomainEvent
+	// name is the stored event name
+	name     string
+	payload  []byte
+	metadata []byte
+	// number is assigned explicitly in shared tables
+	number int
+}
+
+const (
+	// appendColumns is the maximum number of placeholders per appended row
+	appendColumns = 7
+	// maxPlaceholders is the maximum number of placeholders of a prepared statement
+	maxPlaceholders = 65535
+	// appendRowOverhead estimates the size of all other values of an appended row in a statement
+	appendRowOverhead = 128
+)
+
+func (ps PersistenceStrategy) encodeEvents(events []eventstore.DomainEvent) ([]appendRow, error) {
+	rows := make([]appendRow, 0, len(events))
+
+	for _, ev := range events {
+		payload, codec, err := ps.options.serializers.encodePayload(ev.Payload())
+		if err != nil {
+			return nil, err
+		}
+
+		// the default codec is not recorded, so the metadata of JSON events stays readable by other clients
+		if codec != defaultCodec {
+			ev = ev.WithAddedMetadata(CodecMetadataKey, codec)
+		}
+
+		metadata, err := ps.options.serializers.encodeMetadata(ev.Metadata())
 		if err != nil {
 			return nil, err
 		}
 
-		queries = append(queries, "("+query+")")
-		parameters = append(parameters, values...)
+		rows = append(rows, appendRow{event: ev, name: ps.options.storedEventName(ev.Name()), payload: payload, metadata: metadata})
 	}
 
-	groupedQuery := queries[0]
+	return rows, nil
+}
+
+// chunkRows splits the rows into chunks which stay below the placeholder limit and the configured packet size,
+// a single row larger than the packet size gets its own chunk
+func (ps PersistenceStrategy) chunkRows(rows []appendRow) [][]appendRow {
+	chunks := [][]appendRow{}
+	start := 0
+	size := 0
+
+	for i, row := range rows {
+		rowSize := len(row.payload) + len(row.metadata) + appendRowOverhead
+
+		if i > start && (size+rowSize > ps.options.maxPacketSize || (i-start+1)*appendColumns > maxPlaceholders) {
+			chunks = append(chunks, rows[start:i])
+			start = i
+			size = 0
+		}
+
+		size += rowSize
+	}
 
-	if len(queries) > 1 {
-		groupedQuery = strings.Join(queries, " UNION ALL ") + " ORDER BY created_at ASC"
+	if start < len(rows) {
+		chunks = append(chunks, rows[start:])
 	}
 
-	return NewDomainEventIterator(ctx, ps.db, groupedQuery, parameters, count), nil
+	return chunks
+}
+
+// insertRows inserts the rows with a single multi-row statement, the events get their numbers in the order of the rows
+func (ps PersistenceStrategy) insertRows(ctx context.Context, tx *sql.Tx, table streamTable, rows []appendRow) error {
+	columns, values := table.insertColumns()
+
+	placeholder := make([]string, 0, len(rows))
+	parameters := make([]interface{}, 0, len(rows)*appendColumns)
+
+	for _, row := range rows {
+		placeholder = append(placeholder, values)
+		parameters = append(parameters, row.values(table)...)
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s`, table.name, columns, strings.Join(placeholder, ",")),
+		parameters...,
+	)
+
+	return err
 }
 
-func (ps PersistenceStrategy) createQuery(ctx context.Context, streamName string, fromNumber int, matcher eventstore.MetadataMatcher) (string, []interface{}, error) {
-	stmt, err := ps.db.PrepareContext(ctx, fmt.Sprintf(`SELECT COUNT(stream_name) FROM %s WHERE real_stream_name = ?`, EventStreamsTable))
+// insertEach inserts the rows one by one and maps the error of the first failing row
+func (ps PersistenceStrategy) insertEach(ctx context.Context, tx *sql.Tx, streamName string, table streamTable, expectedVersion int, rows []appendRow) error {
+	columns, values := table.insertColumns()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s`, table.name, columns, values))
 	if err != nil {
-		return "", []interface{}{}, err
+		return err
 	}
 	defer stmt.Close()
 
-	var count int
+	for _, row := range rows {
+		_, err = stmt.ExecContext(ctx, row.values(table)...)
+		if err != nil {
+			return ps.mapAppendError(ctx, streamName, table, expectedVersion, row.event, err)
+		}
+	}
 
-	err = stmt.QueryRowContext(ctx, streamName).Scan(&count)
+	return nil
+}
+
+// values of the row in the order of the insert columns of the table
+func (r appendRow) values(table streamTable) []interface{} {
+	values := []interface{}{r.event.UUID().String(), r.name, r.payload, r.metadata, r.event.CreatedAt()}
+
+	if table.shared {
+		return append([]interface{}{table.key, r.number}, values...)
+	}
+
+	return values
+}
+
+// inSavepoint executes fn within a savepoint of the caller's transaction, so a failing fn does not leave partial changes behind
+func inSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	_, err := tx.ExecContext(ctx, `SAVEPOINT event_store_append`)
 	if err != nil {
-		return "", []interface{}{}, err
+		return err
 	}
 
-	if count == 0 {
-		return "", []interface{}{}, eventstore.StreamNotFound{Stream: streamName}
+	err = fn()
+	if err != nil {
+		tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT event_store_append`)
+		return err
 	}
 
-	tableName := GenerateTableName(streamName)
+	_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT event_store_append`)
 
-	wheres, values, err := ps.createWhereClause(matcher)
+	return err
+}
 
-	wheres = append(wheres, `no >= ?`)
-	values = append(values, fromNumber)
+// appendPositions assigns the next global positions to the appended events in the order of their numbers
+func (ps PersistenceStrategy) appendPositions(ctx context.Context, conn execQuerier, table streamTable, events []eventstore.DomainEvent) error {
+	err := ps.lockPositions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	placeholder := make([]string, 0, len(events))
+	parameters := make([]interface{}, 0, len(events))
 
-	whereCondition := fmt.Sprintf(`WHERE %s`, strings.Join(wheres, " AND "))
+	for _, ev := range events {
+		placeholder = append(placeholder, "?")
+		parameters = append(parameters, ev.UUID().String())
+	}
 
-	query := fmt.Sprintf(`SELECT no, event_id, event_name, payload, metadata, created_at, '%s' as stream FROM %s %s ORDER BY no ASC`, streamName, tableName, whereCondition)
+	where, parameters := table.where([]string{fmt.Sprintf(`event_id IN (%s)`, strings.Join(placeholder, ","))}, parameters...)
 
-	return query, values, nil
+	_, err = conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO %s (event_table, event_no) SELECT ?, no FROM %s WHERE %s ORDER BY no ASC`,
+			ps.options.positionsTable(),
+			table.name,
+			where,
+		),
+		append([]interface{}{table.key}, parameters...)...,
+	)
+
+	return err
 }
 
-func (ps PersistenceStrategy) createWhereClause(matcher eventstore.MetadataMatcher) ([]string, []interface{}, error) {
+func (ps PersistenceStrategy) aggregateVersion(ctx context.Context, conn execQuerier, table streamTable, aggregateType, aggregateID string) (int, error) {
+	var version int
+
+	where, values := table.where([]string{`aggregate_type = ?`, `aggregate_id = ?`}, aggregateType, aggregateID)
+
+	err := conn.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT COALESCE(MAX(aggregate_version), 0) FROM %s WHERE %s`, table.name, where),
+		values...,
+	).Scan(&version)
+
+	return version, err
+}
+
+// mapAppendError reads the actual committed version outside of the failed transaction, which may contain earlier events of the append
+func (ps PersistenceStrategy) mapAppendError(ctx context.Context, streamName string, table streamTable, expectedVersion int, ev eventstore.DomainEvent, err error) error {
+	if !isDuplicateKey(err, "ix_unique_event", "ix_event_id") {
+		return err
+	}
+
+	aggregateType := fmt.Sprintf("%v", ev.Metadata()["_aggregate_type"])
+	aggregateID := fmt.Sprintf("%v", ev.Metadata()["_aggregate_id"])
+
+	if expectedVersion == AnyVersion {
+		expectedVersion = ev.Version() - 1
+	}
+
+	conflict := ConcurrencyError{
+		Stream:          streamName,
+		AggregateID:     aggregateID,
+		ExpectedVersion: expectedVersion,
+	}
+
+	version, err := ps.aggregateVersion(ctx, ps.db, table, aggregateType, aggregateID)
+	if err != nil {
+		version = ev.Version()
+	}
+	conflict.ActualVersion = version
+
+	return conflict
+}
+
+func aggregateOf(events []eventstore.DomainEvent) (string, string, error) {
+	aggregateType := fmt.Sprintf("%v", events[0].Metadata()["_aggregate_type"])
+	aggregateID := fmt.Sprintf("%v", events[0].Metadata()["_aggregate_id"])
+
+	for _, ev := range events[1:] {
+		if fmt.Sprintf("%v", ev.Metadata()["_aggregate_type"]) != aggregateType || fmt.Sprintf("%v", ev.Metadata()["_aggregate_id"]) != aggregateID {
+			return "", "", fmt.Errorf("Expected version check requires all events to belong to aggregate %s", aggregateID)
+		}
+	}
+
+	return aggregateType, aggregateID, nil
+}
+
+func (ps PersistenceStrategy) Load(ctx context.Context, streamName string, fromNumber, count int, matcher eventstore.MetadataMatcher) (eventstore.DomainEventIterator, error) {
+	queries, err := ps.createQuery(ctx, streamName, fromNumber, matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.newIterator(ctx, queries, count), nil
+}
+
+// LoadAggregate loads all events of the given aggregate after the given version using the ix_query_aggregate index
+func (ps PersistenceStrategy) LoadAggregate(ctx context.Context, streamName, aggregateType, aggregateID string, afterVersion int) (eventstore.DomainEventIterator, error) {
+	schema, err := ps.FetchStreamSchema(ctx, streamName)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Kind == SimpleSchema {
+		return nil, fmt.Errorf("Loading an aggregate requires the aggregate columns missing in the %s schema of stream %s", schema.Kind, streamName)
+	}
+
+	queries, err := ps.createQuery(ctx, streamName, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if ps.options.tableStrategy == AggregateTypeTable {
+		table := ps.aggregateTable(streamName, aggregateType)
+
+		filtered := []*streamQuery{}
+		for _, query := range queries {
+			if query.tableName == table.name {
+				filtered = append(filtered, query)
+			}
+		}
+		queries = filtered
+	}
+
+	for _, query := range queries {
+		query.wheres = append(query.wheres, `aggregate_type = ?`, `aggregate_id = ?`, `aggregate_version > ?`)
+		query.values = append(query.values, aggregateType, aggregateID, afterVersion)
+	}
+
+	return ps.newIterator(ctx, queries, 0), nil
+}
+
+// MergeAndLoad loads the events of all given streams, ordered by their global position if WithGlobalOrdering is enabled
+// and by their creation date otherwise
+func (ps PersistenceStrategy) MergeAndLoad(ctx context.Context, count int, streams ...eventstore.LoadStreamParameter) (eventstore.DomainEventIterator, error) {
+	if ps.options.globalOrdering {
+		return ps.LoadFromPosition(ctx, 0, count, streams...)
+	}
+
+	queries, err := ps.createQueries(ctx, streams)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.newIterator(ctx, queries, count), nil
+}
+
+// LoadFromPosition loads the events of all given streams in their global order, starting after the given global position
+// The global position of each event is available as "position" metadata
+// checkPositions returns MissingEventPositions if an event of the queries has no position, it would never be loaded by position
+func (ps PersistenceStrategy) checkPositions(ctx context.Context, queries []*streamQuery) error {
+	for _, q := range queries {
+		wheres := append(append([]string{}, q.wheres...), `no > ?`, `p.position IS NULL`)
+		parameters := append(append([]interface{}{q.tableKey}, q.values...), q.from)
+
+		var no int
+
+		err := ps.db.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT no FROM %s LEFT JOIN %s p ON p.event_table = ? AND p.event_no = no WHERE %s ORDER BY no ASC LIMIT 1`,
+			q.tableName,
+			ps.options.positionsTable(),
+			strings.Join(wheres, " AND "),
+		), parameters...).Scan(&no)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		return MissingEventPositions{Stream: q.streamName, Number: no}
+	}
+
+	return nil
+}
+
+func (ps PersistenceStrategy) LoadFromPosition(ctx context.Context, fromPosition, count int, streams ...eventstore.LoadStreamParameter) (eventstore.DomainEventIterator, error) {
+	if !ps.options.globalOrdering {
+		return nil, fmt.Errorf("Loading by global position requires the WithGlobalOrdering option")
+	}
+
+	queries, err := ps.createQueries(ctx, streams)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ps.checkPositions(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	it := ps.newIterator(ctx, queries, count)
+	it.positionsTable = ps.options.positionsTable()
+	it.lastPosition = fromPosition
+
+	return it, nil
+}
+
+func (ps PersistenceStrategy) newIterator(ctx context.Context, queries []*streamQuery, count int) *DomainEventIterator {
+	it := newDomainEventIterator(ctx, ps.db, ps.options.typeRegistry, queries, count)
+	it.gapDetection = ps.options.gapDetection
+	it.serializers = ps.options.serializers
+	it.upcasters = ps.options.upcasters
+	it.unknownEvents = ps.options.unknownEvents
+	if ps.options.prooph != nil {
+		it.eventNames = ps.options.prooph.EventNames
+		it.prooph = true
+	}
+
+	return it
+}
+
+func (ps PersistenceStrategy) createQueries(ctx context.Context, streams []eventstore.LoadStreamParameter) ([]*streamQuery, error) {
+	queries := make([]*streamQuery, 0, len(streams))
+
+	for _, stream := range streams {
+		query, err := ps.createQuery(ctx, stream.StreamName, stream.FromNumber, stream.Matcher)
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, query...)
+	}
+
+	return queries, nil
+}
+
+// createQuery returns a query for each table with rows of the EventStream
+func (ps PersistenceStrategy) createQuery(ctx context.Context, streamName string, fromNumber int, matcher eventstore.MetadataMatcher) ([]*streamQuery, error) {
+	schema, err := ps.FetchStreamSchema(ctx, streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	wheres, values, err := ps.createWhereClause(matcher, schema.columns())
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := ps.streamTables(ctx, ps.db, streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]*streamQuery, 0, len(tables))
+
+	for _, table := range tables {
+		wheres, values := table.scope(wheres, values)
+
+		queries = append(queries, &streamQuery{
+			streamName: streamName,
+			tableName:  table.name,
+			tableKey:   table.key,
+			wheres:     wheres,
+			values:     values,
+			cursor:     fromNumber - 1,
+			from:       fromNumber - 1,
+		})
+	}
+
+	return queries, nil
+}
+
+// createWhereClause filters metadata fields with the given generated columns directly, so their indexes are used
+func (ps PersistenceStrategy) createWhereClause(matcher eventstore.MetadataMatcher, columns map[string]string) ([]string, []interface{}, error) {
 	var wheres []string
 	var values []interface{}
 
@@ -331,6 +1266,19 @@ func (ps PersistenceStrategy) createWhereClause(matcher eventstore.MetadataMatch
 			}
 		}
 
+		if column, ok := columns[match.Field]; ok && match.FieldType == eventstore.MetadataField {
+			switch v := match.Value.(type) {
+			case bool:
+				wheres = append(wheres, fmt.Sprintf(`%s %s`, column, expression(strconv.FormatBool(v))))
+			default:
+				values = append(values, match.Value)
+
+				wheres = append(wheres, fmt.Sprintf(`%s %s`, column, expression("?")))
+			}
+
+			continue
+		}
+
 		if match.FieldType == eventstore.MetadataField {
 			switch v := match.Value.(type) {
 			case bool:
@@ -349,7 +1297,7 @@ func (ps PersistenceStrategy) createWhereClause(matcher eventstore.MetadataMatch
 			default:
 				values = append(values, match.Value)
 
-				wheres = append(wheres, `%s %s`, match.Field, expression(("?")))
+				wheres = append(wheres, fmt.Sprintf(`%s %s`, match.Field, expression("?")))
 			}
 		}
 	}
@@ -357,8 +1305,9 @@ func (ps PersistenceStrategy) createWhereClause(matcher eventstore.MetadataMatch
 	return wheres, values, nil
 }
 
-func NewPersistenceStrategy(db *sql.DB) *PersistenceStrategy {
+func NewPersistenceStrategy(db *sql.DB, opts ...Option) *PersistenceStrategy {
 	return &PersistenceStrategy{
-		db: db,
+		db:      db,
+		options: newOptions(opts),
 	}
 }
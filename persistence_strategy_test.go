@@ -3,6 +3,8 @@ package mysql_test
 import (
 	"context"
 	"database/sql"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +15,20 @@ import (
 )
 
 func Test_MysqlEventStore(t *testing.T) {
+	t.Run("TablePerStream", func(t *testing.T) {
+		testEventStore(t, mysql.TablePerStream)
+	})
+
+	t.Run("SingleTable", func(t *testing.T) {
+		testEventStore(t, mysql.SingleTable)
+	})
+
+	t.Run("AggregateTypeTable", func(t *testing.T) {
+		testEventStore(t, mysql.AggregateTypeTable)
+	})
+}
+
+func testEventStore(t *testing.T, strategy mysql.TableStrategy) {
 	ctx := context.Background()
 	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
 	if err != nil {
@@ -23,7 +39,12 @@ func Test_MysqlEventStore(t *testing.T) {
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(10)
 
-	eventStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db))
+	newStrategy := func(opts ...mysql.Option) *mysql.PersistenceStrategy {
+		return mysql.NewPersistenceStrategy(db, append(opts, mysql.WithTableStrategy(strategy))...)
+	}
+
+	ps := newStrategy()
+	eventStore := eventstore.NewEventStore(ps)
 	err = eventStore.Install(ctx)
 	if err != nil {
 		t.Error(err)
@@ -297,4 +318,852 @@ func Test_MysqlEventStore(t *testing.T) {
 			t.Error("Expected only one result Event")
 		}
 	})
+
+	t.Run("AppendTo with expected version returns ConcurrencyError", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		aggregateID := uuid.NewV4()
+
+		err = ps.AppendToWithExpectedVersion(ctx, "foo-stream", 0, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(1),
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(2),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ps.AppendToWithExpectedVersion(ctx, "foo-stream", 1, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(2),
+		})
+		conflict, ok := err.(mysql.ConcurrencyError)
+		if !ok {
+			t.Fatalf("Expected a ConcurrencyError, got %v", err)
+		}
+		if conflict.ExpectedVersion != 1 || conflict.ActualVersion != 2 {
+			t.Errorf("Unexpected versions in %v", conflict)
+		}
+
+		err = ps.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(2),
+		})
+		if _, ok := err.(mysql.ConcurrencyError); !ok {
+			t.Fatalf("Expected a ConcurrencyError for a duplicate version, got %v", err)
+		}
+	})
+
+	t.Run("Load pages through EventStreams larger than one page", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		events := make([]eventstore.DomainEvent, 0, 2500)
+		for i := 0; i < 2500; i++ {
+			events = append(events, eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()))
+		}
+
+		err = eventStore.AppendTo(ctx, "foo-stream", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := eventStore.Load(ctx, "foo-stream", 2, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2499 {
+			t.Fatalf("Expected 2499 events, got %d", len(list))
+		}
+
+		for i, ev := range list {
+			if ev.Number() != i+2 {
+				t.Fatalf("Expected event number %d, got %d", i+2, ev.Number())
+			}
+		}
+	})
+
+	t.Run("MergeAndLoad pages through events created out of order", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.CreateStream(ctx, "bar-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "bar-stream")
+
+		now := time.Now()
+
+		events := make([]eventstore.DomainEvent, 0, 1500)
+		for i := 0; i < 1500; i++ {
+			events = append(events, eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, now.Add(-time.Duration(i)*time.Second)))
+		}
+
+		err = eventStore.AppendTo(ctx, "foo-stream", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.AppendTo(ctx, "bar-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, now),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := eventStore.MergeAndLoad(ctx, 0, []eventstore.LoadStreamParameter{
+			{StreamName: "foo-stream", FromNumber: 1},
+			{StreamName: "bar-stream", FromNumber: 1},
+		}...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 1501 {
+			t.Fatalf("Expected 1501 events, got %d", len(list))
+		}
+
+		if list[0].Number() != 1500 {
+			t.Errorf("Expected the earliest created event first, got number %d", list[0].Number())
+		}
+	})
+
+	t.Run("Concurrent appends to the same EventStream are serialized", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				errs <- eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+					eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()).WithVersion(1),
+					eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()).WithVersion(1),
+				})
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		it, err := eventStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 20 {
+			t.Fatalf("Expected 20 events, got %d", len(list))
+		}
+
+		for i, ev := range list {
+			if ev.Number() != i+1 {
+				t.Fatalf("Expected event number %d, got %d", i+1, ev.Number())
+			}
+		}
+	})
+
+	t.Run("AppendTo in multiple batches keeps the event order", func(t *testing.T) {
+		batched := newStrategy(mysql.WithMaxPacketSize(1024))
+
+		err := batched.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer batched.DeleteStream(ctx, "foo-stream")
+
+		aggregateID := uuid.NewV4()
+
+		events := make([]eventstore.DomainEvent, 0, 50)
+		for i := 1; i <= 50; i++ {
+			events = append(events, eventstore.NewDomainEvent(aggregateID, TestEvent{Foo: strconv.Itoa(i)}, nil, time.Now()).WithVersion(i))
+		}
+
+		err = batched.AppendTo(ctx, "foo-stream", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := batched.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 50 {
+			t.Fatalf("Expected 50 events, got %d", len(list))
+		}
+
+		for i, ev := range list {
+			if ev.Number() != i+1 || ev.Payload().(TestEvent).Foo != strconv.Itoa(i+1) {
+				t.Fatalf("Expected event %d in order, got %d with %v", i+1, ev.Number(), ev.Payload())
+			}
+		}
+
+		err = batched.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(51),
+			eventstore.NewDomainEvent(aggregateID, TestEvent{}, nil, time.Now()).WithVersion(30),
+		})
+		conflict, ok := err.(mysql.ConcurrencyError)
+		if !ok {
+			t.Fatalf("Expected a ConcurrencyError, got %v", err)
+		}
+		if conflict.ExpectedVersion != 29 || conflict.ActualVersion != 50 {
+			t.Errorf("Unexpected versions in %v", conflict)
+		}
+	})
+
+	t.Run("MergeAndLoad with global ordering", func(t *testing.T) {
+		orderedStore := eventstore.NewEventStore(newStrategy(mysql.WithGlobalOrdering()))
+		err := orderedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = orderedStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer orderedStore.DeleteStream(ctx, "foo-stream")
+
+		err = orderedStore.CreateStream(ctx, "bar-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer orderedStore.DeleteStream(ctx, "bar-stream")
+
+		now := time.Now()
+		uuids := []uuid.UUID{uuid.NewV4(), uuid.NewV4(), uuid.NewV4()}
+
+		for i, stream := range []string{"bar-stream", "foo-stream", "bar-stream"} {
+			err = orderedStore.AppendTo(ctx, stream, []eventstore.DomainEvent{
+				eventstore.NewDomainEvent(uuids[i], TestEvent{}, nil, now),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		it, err := orderedStore.MergeAndLoad(ctx, 0, []eventstore.LoadStreamParameter{
+			{StreamName: "foo-stream", FromNumber: 1},
+			{StreamName: "bar-stream", FromNumber: 1},
+		}...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(list))
+		}
+
+		for i, ev := range list {
+			if ev.AggregateID() != uuids[i] {
+				t.Errorf("Expected event %d in global order", i+1)
+			}
+		}
+	})
+
+	t.Run("Enabling global ordering backfills the positions of existing events", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		orderedStore := eventstore.NewEventStore(newStrategy(mysql.WithGlobalOrdering(), mysql.WithEventPositionsTable("backfilled_event_positions")))
+		err = orderedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.ExecContext(ctx, "DROP TABLE IF EXISTS backfilled_event_positions")
+
+		err = orderedStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := orderedStore.MergeAndLoad(ctx, 0, eventstore.LoadStreamParameter{StreamName: "foo-stream", FromNumber: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(list))
+		}
+	})
+
+	t.Run("MergeAndLoad fails on events without a global position", func(t *testing.T) {
+		orderedStore := eventstore.NewEventStore(newStrategy(mysql.WithGlobalOrdering(), mysql.WithEventPositionsTable("missing_event_positions")))
+		err := orderedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.ExecContext(ctx, "DROP TABLE IF EXISTS missing_event_positions")
+
+		err = orderedStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer orderedStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = orderedStore.MergeAndLoad(ctx, 0, eventstore.LoadStreamParameter{StreamName: "foo-stream", FromNumber: 1})
+		if _, ok := err.(mysql.MissingEventPositions); !ok {
+			t.Fatalf("Expected MissingEventPositions, got %v", err)
+		}
+
+		err = orderedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := orderedStore.MergeAndLoad(ctx, 0, eventstore.LoadStreamParameter{StreamName: "foo-stream", FromNumber: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 1 {
+			t.Fatalf("Expected 1 event, got %d", len(list))
+		}
+	})
+
+	t.Run("Load waits for gaps of uncommitted events", func(t *testing.T) {
+		if strategy != mysql.TablePerStream {
+			t.Skip("Events of shared tables are numbered while their EventStream is locked, so they have no gaps")
+		}
+
+		gapStore := eventstore.NewEventStore(newStrategy(mysql.WithGapDetection(mysql.GapDetection{
+			Timeout: 5 * time.Second,
+		})))
+
+		err := gapStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gapStore.DeleteStream(ctx, "foo-stream")
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO "+mysql.GenerateTableName("foo-stream")+" (event_id, event_name, payload, metadata, created_at) VALUES (?, ?, ?, ?, ?)",
+			uuid.NewV4().String(),
+			"TestEvent",
+			"{}",
+			`{"_aggregate_id": "`+uuid.NewV4().String()+`", "_aggregate_type": "", "_aggregate_version": 1}`,
+			time.Now(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = gapStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			tx.Commit()
+		}()
+
+		it, err := gapStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2 || list[0].Number() != 1 || list[1].Number() != 2 {
+			t.Fatalf("Expected both events in order, got %d events", len(list))
+		}
+	})
+
+	t.Run("Load skips gaps of rolled back transactions", func(t *testing.T) {
+		if strategy != mysql.TablePerStream {
+			t.Skip("Events of shared tables are numbered while their EventStream is locked, so they have no gaps")
+		}
+
+		gapStore := eventstore.NewEventStore(newStrategy(mysql.WithGapDetection(mysql.GapDetection{
+			Timeout: 5 * time.Second,
+		})))
+
+		err := gapStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gapStore.DeleteStream(ctx, "foo-stream")
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO "+mysql.GenerateTableName("foo-stream")+" (event_id, event_name, payload, metadata, created_at) VALUES (?, ?, ?, ?, ?)",
+			uuid.NewV4().String(),
+			"TestEvent",
+			"{}",
+			`{"_aggregate_id": "`+uuid.NewV4().String()+`", "_aggregate_type": "", "_aggregate_version": 1}`,
+			time.Now(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = gapStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			tx.Rollback()
+		}()
+
+		start := time.Now()
+
+		it, err := gapStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 1 || list[0].Number() != 2 {
+			t.Fatalf("Expected the committed event, got %d events", len(list))
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("Expected the rolled back gap to be skipped without waiting for the timeout, took %s", time.Since(start))
+		}
+	})
+
+	t.Run("Stream Metadata", func(t *testing.T) {
+		err := ps.CreateStream(ctx, "foo-stream", mysql.WithStreamMetadata(map[string]interface{}{"owner": "billing"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		metadata, err := ps.FetchStreamMetadata(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata["owner"] != "billing" {
+			t.Errorf("Expected owner metadata, got %v", metadata)
+		}
+
+		err = ps.UpdateStreamMetadata(ctx, "foo-stream", map[string]interface{}{"owner": "shipping", "retention": "30d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metadata, err = ps.FetchStreamMetadata(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata["owner"] != "shipping" || metadata["retention"] != "30d" {
+			t.Errorf("Expected updated metadata, got %v", metadata)
+		}
+
+		err = ps.UpdateStreamMetadata(ctx, "bar-stream", map[string]interface{}{})
+		if _, ok := err.(eventstore.StreamNotFound); !ok {
+			t.Errorf("Expected a StreamNotFound error, got %v", err)
+		}
+	})
+
+	t.Run("List filtered Streams", func(t *testing.T) {
+		for _, stream := range []string{"list_a-1", "list_a-2", "list_b-1"} {
+			err := ps.CreateStream(ctx, stream, mysql.WithStreamMetadata(map[string]interface{}{"owner": stream[:6]}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer eventStore.DeleteStream(ctx, stream)
+		}
+
+		streams, err := ps.ListStreams(ctx, mysql.StreamFilter{Prefix: "list_a-", Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].Name != "list_a-1" {
+			t.Fatalf("Expected first page with list_a-1, got %v", streams)
+		}
+		if streams[0].TableName != mysql.GenerateTableName("list_a-1") {
+			t.Errorf("Unexpected table name %s", streams[0].TableName)
+		}
+
+		streams, err = ps.ListStreams(ctx, mysql.StreamFilter{Prefix: "list_a-", Limit: 1, After: streams[0].No})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].Name != "list_a-2" {
+			t.Fatalf("Expected second page with list_a-2, got %v", streams)
+		}
+
+		streams, err = ps.ListStreams(ctx, mysql.StreamFilter{
+			Regex: "^list_",
+			Metadata: eventstore.MetadataMatcher{
+				{Field: "owner", FieldType: eventstore.MetadataField, Operation: eventstore.EqualsOperator, Value: "list_b"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].Name != "list_b-1" {
+			t.Fatalf("Expected list_b-1 filtered by metadata, got %v", streams)
+		}
+	})
+
+	t.Run("Isolated EventStore with custom table names", func(t *testing.T) {
+		isolated := newStrategy(
+			mysql.WithDatabase("event-store"),
+			mysql.WithTablePrefix("billing_"),
+			mysql.WithEventStreamsTable("billing_event_streams"),
+			mysql.WithProjectionsTable("billing_projections"),
+			mysql.WithEventsTable("billing_events"),
+		)
+		isolatedStore := eventstore.NewEventStore(isolated)
+
+		err := isolatedStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.ExecContext(ctx, "DROP TABLE IF EXISTS billing_event_streams, billing_projections, billing_events")
+
+		err = isolatedStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer isolatedStore.DeleteStream(ctx, "foo-stream")
+
+		ok, err := eventStore.HasStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Fatal("Expected stream only in the isolated EventStreams table")
+		}
+
+		streams, err := isolated.ListStreams(ctx, mysql.StreamFilter{Name: "foo-stream"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].TableName[:8] != "billing_" {
+			t.Fatalf("Expected prefixed stream table, got %v", streams)
+		}
+
+		err = isolatedStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := isolatedStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		empty, err := it.IsEmpty()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if empty {
+			t.Fatal("Expected appended event in the prefixed stream table")
+		}
+	})
+
+	t.Run("Load unknown event types", func(t *testing.T) {
+		type UnknownTestEvent struct {
+			Bar string
+		}
+
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), UnknownTestEvent{Bar: "bar"}, nil, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "foo"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := eventStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = it.ToList()
+		if _, ok := err.(mysql.UnknownEventType); !ok {
+			t.Errorf("Expected an UnknownEventType error, got %v", err)
+		}
+
+		it, err = newStrategy(mysql.WithUnknownEventPolicy(mysql.UnknownEventSkip)).Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Name() != "TestEvent" {
+			t.Errorf("Expected only the known event, got %v", list)
+		}
+
+		it, err = newStrategy(mysql.WithUnknownEventPolicy(mysql.UnknownEventRaw)).Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err = it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("Expected 2 events, got %d", len(list))
+		}
+
+		raw, ok := list[0].Payload().(mysql.RawEvent)
+		if !ok || raw.Name != "UnknownTestEvent" || string(raw.Payload) != `{"Bar": "bar"}` {
+			t.Errorf("Expected a RawEvent payload, got %v", list[0].Payload())
+		}
+	})
+
+	t.Run("AppendTo within a caller transaction", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		client := mysql.NewClient(db)
+
+		_, err = db.ExecContext(ctx, "CREATE TABLE tx_read_model (name VARCHAR(150) NOT NULL) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Delete(ctx, "tx_read_model")
+
+		count := func() (int, int) {
+			it, err := ps.Load(ctx, "foo-stream", 1, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list, err := it.ToList()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rows int
+
+			err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tx_read_model").Scan(&rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return len(list), rows
+		}
+
+		for _, commit := range []bool{false, true} {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ps.WithTx(tx).AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+				eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "foo"}, nil, time.Now()),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.WithTx(tx).Insert(ctx, "tx_read_model", map[string]interface{}{"name": "foo"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if commit {
+				err = tx.Commit()
+			} else {
+				err = tx.Rollback()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events, rows := count()
+			if !commit && (events != 0 || rows != 0) {
+				t.Errorf("Expected nothing after rollback, got %d events and %d rows", events, rows)
+			}
+			if commit && (events != 1 || rows != 1) {
+				t.Errorf("Expected 1 event and 1 row after commit, got %d events and %d rows", events, rows)
+			}
+		}
+	})
+
+	t.Run("AppendTo routes events by aggregate type", func(t *testing.T) {
+		if strategy != mysql.AggregateTypeTable {
+			t.Skip("Only the AggregateTypeTable strategy routes events by aggregate type")
+		}
+
+		err := eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.CreateStream(ctx, "bar-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "bar-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "1"}, nil, time.Now()).WithAggregateType("Order"),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "2"}, nil, time.Now()).WithAggregateType("Customer"),
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "3"}, nil, time.Now()).WithAggregateType("Order"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.AppendTo(ctx, "bar-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "4"}, nil, time.Now()).WithAggregateType("Customer"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, aggregateType := range []string{"Order", "Customer"} {
+			ok, err := ps.HasStream(ctx, "$aggregate-"+aggregateType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatalf("Expected a table for aggregate type %s", aggregateType)
+			}
+		}
+
+		it, err := eventStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 3 {
+			t.Fatalf("Expected 3 events of the logical stream, got %d", len(list))
+		}
+		for i, ev := range list {
+			if ev.Number() != i+1 || ev.Payload().(TestEvent).Foo != strconv.Itoa(i+1) {
+				t.Errorf("Expected event %d in append order, got %d with %v", i+1, ev.Number(), ev.Payload())
+			}
+		}
+
+		it, err = eventStore.MergeAndLoad(ctx, 0, eventstore.LoadStreamParameter{StreamName: "foo-stream", FromNumber: 3}, eventstore.LoadStreamParameter{StreamName: "bar-stream", FromNumber: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err = it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2 || list[0].Payload().(TestEvent).Foo != "3" || list[1].Payload().(TestEvent).Foo != "4" {
+			t.Errorf("Expected the merged events of both logical streams, got %v", list)
+		}
+	})
 }
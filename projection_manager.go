@@ -5,33 +5,56 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	eventstore "github.com/go-event-store/eventstore"
 	_ "github.com/go-sql-driver/mysql"
 )
 
+const (
+	lockNow   = `DATE_FORMAT(UTC_TIMESTAMP(6), '%Y-%m-%dT%H:%i:%s.%f')`
+	lockUntil = `DATE_FORMAT(UTC_TIMESTAMP(6) + INTERVAL ? MICROSECOND, '%Y-%m-%dT%H:%i:%s.%f')`
+)
+
 type ProjectionManager struct {
-	db *sql.DB
+	db      *sql.DB
+	tx      *sql.Tx
+	options options
+}
+
+// WithTx returns a copy of the ProjectionManager executing all statements within the given transaction controlled by the caller
+func (pm ProjectionManager) WithTx(tx *sql.Tx) *ProjectionManager {
+	pm.tx = tx
+
+	return &pm
+}
+
+func (pm ProjectionManager) conn() execQuerier {
+	if pm.tx != nil {
+		return pm.tx
+	}
+
+	return pm.db
 }
 
 func (pm ProjectionManager) FetchProjectionStatus(ctx context.Context, projectionName string) (eventstore.Status, error) {
 	var status eventstore.Status
 
-	row := pm.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT status FROM %s WHERE name = ?;`, ProjectionsTable), projectionName)
+	row := pm.conn().QueryRowContext(ctx, fmt.Sprintf(`SELECT status FROM %s WHERE name = ?;`, pm.options.projectionsTable()), projectionName)
 	err := row.Scan(&status)
 
 	return status, err
 }
 
 func (pm ProjectionManager) CreateProjection(ctx context.Context, projectionName string, state interface{}, status eventstore.Status) error {
-	data, err := json.Marshal(state)
+	data, err := pm.options.encodeProjectionState(state)
 	if err != nil {
 		return err
 	}
 
-	_, err = pm.db.ExecContext(
+	_, err = pm.conn().ExecContext(
 		ctx,
-		fmt.Sprintf(`INSERT INTO %s (name, position, state, status, locked_until) VALUES (?, ?, ?, ?, NULL)`, ProjectionsTable),
+		fmt.Sprintf(`INSERT INTO %s (name, position, state, status, locked_until) VALUES (?, ?, ?, ?, NULL)`, pm.options.projectionsTable()),
 		projectionName,
 		"{}",
 		data,
@@ -42,7 +65,7 @@ func (pm ProjectionManager) CreateProjection(ctx context.Context, projectionName
 }
 
 func (pm ProjectionManager) DeleteProjection(ctx context.Context, projectionName string) error {
-	r, err := pm.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE name = ?`, ProjectionsTable), projectionName)
+	r, err := pm.conn().ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE name = ?`, pm.options.projectionsTable()), projectionName)
 	c, err := r.RowsAffected()
 	if c == 0 {
 		return eventstore.ProjectionNotFound{Name: projectionName}
@@ -52,14 +75,14 @@ func (pm ProjectionManager) DeleteProjection(ctx context.Context, projectionName
 }
 
 func (pm ProjectionManager) ResetProjection(ctx context.Context, projectionName string, state interface{}) error {
-	data, err := json.Marshal(state)
+	data, err := pm.options.encodeProjectionState(state)
 	if err != nil {
 		return err
 	}
 
-	r, err := pm.db.ExecContext(
+	r, err := pm.conn().ExecContext(
 		ctx,
-		fmt.Sprintf(`UPDATE %s SET status = ?, state = ?, position = ? WHERE name = ?`, ProjectionsTable),
+		fmt.Sprintf(`UPDATE %s SET status = ?, state = ?, position = ? WHERE name = ?`, pm.options.projectionsTable()),
 		eventstore.StatusIdle,
 		data,
 		"{}",
@@ -75,7 +98,7 @@ func (pm ProjectionManager) ResetProjection(ctx context.Context, projectionName
 }
 
 func (pm ProjectionManager) PersistProjection(ctx context.Context, projectionName string, state interface{}, streamPositions map[string]int) error {
-	data, err := json.Marshal(state)
+	data, err := pm.options.encodeProjectionState(state)
 	if err != nil {
 		return err
 	}
@@ -85,9 +108,9 @@ func (pm ProjectionManager) PersistProjection(ctx context.Context, projectionNam
 		return err
 	}
 
-	r, err := pm.db.ExecContext(
+	r, err := pm.conn().ExecContext(
 		ctx,
-		fmt.Sprintf(`UPDATE %s SET status = ?, state = ?, position = ? WHERE name = ?`, ProjectionsTable),
+		fmt.Sprintf(`UPDATE %s SET status = ?, state = ?, position = ? WHERE name = ?`, pm.options.projectionsTable()),
 		eventstore.StatusIdle,
 		data,
 		positions,
@@ -103,9 +126,9 @@ func (pm ProjectionManager) PersistProjection(ctx context.Context, projectionNam
 }
 
 func (pm ProjectionManager) UpdateProjectionStatus(ctx context.Context, projectionName string, status eventstore.Status) error {
-	r, err := pm.db.ExecContext(
+	r, err := pm.conn().ExecContext(
 		ctx,
-		fmt.Sprintf(`UPDATE %s SET status = ? WHERE name = ?`, ProjectionsTable),
+		fmt.Sprintf(`UPDATE %s SET status = ? WHERE name = ?`, pm.options.projectionsTable()),
 		status,
 		projectionName,
 	)
@@ -122,7 +145,7 @@ func (pm ProjectionManager) LoadProjection(ctx context.Context, projectionName s
 	position := map[string]int{}
 	var state interface{}
 
-	row := pm.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT position, state FROM %s WHERE name = ? LIMIT 1`, ProjectionsTable), projectionName)
+	row := pm.conn().QueryRowContext(ctx, fmt.Sprintf(`SELECT position, state FROM %s WHERE name = ? LIMIT 1`, pm.options.projectionsTable()), projectionName)
 
 	var stateBytes []byte
 	var positionBytes []byte
@@ -132,6 +155,11 @@ func (pm ProjectionManager) LoadProjection(ctx context.Context, projectionName s
 		return position, state, eventstore.ProjectionNotFound{Name: projectionName}
 	}
 
+	if pm.options.prooph != nil {
+		stateBytes = proophObject(stateBytes)
+		positionBytes = proophObject(positionBytes)
+	}
+
 	json.Unmarshal(stateBytes, &state)
 	json.Unmarshal(positionBytes, &position)
 
@@ -141,7 +169,7 @@ func (pm ProjectionManager) LoadProjection(ctx context.Context, projectionName s
 func (pm ProjectionManager) ProjectionExists(ctx context.Context, projectionName string) (bool, error) {
 	var name string
 
-	row := pm.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT name FROM %s WHERE name = ?;`, ProjectionsTable), projectionName)
+	row := pm.conn().QueryRowContext(ctx, fmt.Sprintf(`SELECT name FROM %s WHERE name = ?;`, pm.options.projectionsTable()), projectionName)
 	err := row.Scan(&name)
 	if err == sql.ErrNoRows {
 		return false, nil
@@ -153,6 +181,140 @@ func (pm ProjectionManager) ProjectionExists(ctx context.Context, projectionName
 	return true, err
 }
 
-func NewProjectionManager(db *sql.DB) *ProjectionManager {
-	return &ProjectionManager{db: db}
+// AcquireLock claims the projection for the given instance until the lease expires
+// It fails with ProjectionLocked while another instance holds an unexpired lock
+// In the prooph compatibility mode the owner is not recorded, so an instance cannot reacquire its own unexpired lock
+func (pm ProjectionManager) AcquireLock(ctx context.Context, projectionName, instanceID string, lease time.Duration) error {
+	if pm.options.prooph != nil {
+		r, err := pm.conn().ExecContext(
+			ctx,
+			fmt.Sprintf(
+				`UPDATE %s SET locked_until = %s WHERE name = ? AND (locked_until IS NULL OR locked_until < %s)`,
+				pm.options.projectionsTable(),
+				lockUntil,
+				lockNow,
+			),
+			lease.Microseconds(),
+			projectionName,
+		)
+		if err != nil {
+			return err
+		}
+
+		return pm.checkLock(ctx, r, projectionName)
+	}
+
+	r, err := pm.conn().ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`UPDATE %s SET locked_until = %s, locked_by = ? WHERE name = ? AND (locked_until IS NULL OR locked_until < %s OR locked_by = ?)`,
+			pm.options.projectionsTable(),
+			lockUntil,
+			lockNow,
+		),
+		lease.Microseconds(),
+		instanceID,
+		projectionName,
+		instanceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return pm.checkLock(ctx, r, projectionName)
+}
+
+// RenewLock extends the lease of a lock held by the given instance
+// In the prooph compatibility mode any held lock of the projection is extended, like prooph does
+func (pm ProjectionManager) RenewLock(ctx context.Context, projectionName, instanceID string, lease time.Duration) error {
+	if pm.options.prooph != nil {
+		r, err := pm.conn().ExecContext(
+			ctx,
+			fmt.Sprintf(`UPDATE %s SET locked_until = %s WHERE name = ? AND locked_until IS NOT NULL`, pm.options.projectionsTable(), lockUntil),
+			lease.Microseconds(),
+			projectionName,
+		)
+		if err != nil {
+			return err
+		}
+
+		return pm.checkLock(ctx, r, projectionName)
+	}
+
+	r, err := pm.conn().ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s SET locked_until = %s WHERE name = ? AND locked_by = ?`, pm.options.projectionsTable(), lockUntil),
+		lease.Microseconds(),
+		projectionName,
+		instanceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return pm.checkLock(ctx, r, projectionName)
+}
+
+// ReleaseLock frees a lock held by the given instance, in the prooph compatibility mode the lock is freed regardless of its owner
+func (pm ProjectionManager) ReleaseLock(ctx context.Context, projectionName, instanceID string) error {
+	if pm.options.prooph != nil {
+		_, err := pm.conn().ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET locked_until = NULL WHERE name = ?`, pm.options.projectionsTable()), projectionName)
+
+		return err
+	}
+
+	r, err := pm.conn().ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s SET locked_until = NULL, locked_by = NULL WHERE name = ? AND locked_by = ?`, pm.options.projectionsTable()),
+		projectionName,
+		instanceID,
+	)
+	if err != nil {
+		return err
+	}
+
+	c, err := r.RowsAffected()
+	if err != nil || c > 0 {
+		return err
+	}
+
+	owner, lockedUntil, err := pm.fetchLock(ctx, projectionName)
+	if err != nil {
+		return err
+	}
+	if owner.Valid {
+		return ProjectionLocked{Name: projectionName, Owner: owner.String, LockedUntil: lockedUntil.String}
+	}
+
+	return nil
+}
+
+func (pm ProjectionManager) checkLock(ctx context.Context, r sql.Result, projectionName string) error {
+	c, err := r.RowsAffected()
+	if err != nil || c > 0 {
+		return err
+	}
+
+	owner, lockedUntil, err := pm.fetchLock(ctx, projectionName)
+	if err != nil {
+		return err
+	}
+
+	return ProjectionLocked{Name: projectionName, Owner: owner.String, LockedUntil: lockedUntil.String}
+}
+
+func (pm ProjectionManager) fetchLock(ctx context.Context, projectionName string) (sql.NullString, sql.NullString, error) {
+	var owner, lockedUntil sql.NullString
+
+	row := pm.conn().QueryRowContext(ctx, fmt.Sprintf(`SELECT %s, locked_until FROM %s WHERE name = ?`, pm.options.lockedByColumn(), pm.options.projectionsTable()), projectionName)
+	err := row.Scan(&owner, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return owner, lockedUntil, eventstore.ProjectionNotFound{Name: projectionName}
+	}
+
+	return owner, lockedUntil, err
+}
+
+func NewProjectionManager(db *sql.DB, opts ...Option) *ProjectionManager {
+	return &ProjectionManager{db: db, options: newOptions(opts)}
 }
@@ -295,4 +295,49 @@ func Test_MysqlProjector(t *testing.T) {
 			t.Error("Projection should return in historical order")
 		}
 	})
+
+	t.Run("Lock Projection", func(t *testing.T) {
+		err := pm.CreateProjection(ctx, "lock_test", map[string]interface{}{}, eventstore.StatusIdle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pm.DeleteProjection(ctx, "lock_test")
+
+		err = pm.AcquireLock(ctx, "lock_test", "instance-1", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pm.AcquireLock(ctx, "lock_test", "instance-2", time.Minute)
+		if locked, ok := err.(mysql.ProjectionLocked); !ok || locked.Owner != "instance-1" {
+			t.Fatalf("Expected a ProjectionLocked error, got %v", err)
+		}
+
+		err = pm.RenewLock(ctx, "lock_test", "instance-1", time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		err = pm.AcquireLock(ctx, "lock_test", "instance-2", time.Minute)
+		if err != nil {
+			t.Fatalf("Expected takeover of an expired lock, got %v", err)
+		}
+
+		err = pm.RenewLock(ctx, "lock_test", "instance-1", time.Minute)
+		if _, ok := err.(mysql.ProjectionLocked); !ok {
+			t.Fatalf("Expected a ProjectionLocked error, got %v", err)
+		}
+
+		err = pm.ReleaseLock(ctx, "lock_test", "instance-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pm.AcquireLock(ctx, "lock_test", "instance-1", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
 }
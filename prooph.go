@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+// Prooph configures the compatibility mode with databases created by the MySQL implementation of the prooph pdo-event-store,
+// so PHP and Go services can share one event store
+//
+// The EventStreams table stores the prooph category instead of the stream schema, whose kind is detected from the stream table,
+// and projections are locked by their locked_until lease only, like prooph does. Only the TablePerStream strategy is supported.
+type Prooph struct {
+	// EventNames maps the event names stored by prooph, usually PHP class names, to the names of the registered Go types
+	EventNames map[string]string
+}
+
+// proophCategory returns the category of a stream name like prooph, the part before the first dash
+func proophCategory(streamName string) interface{} {
+	i := strings.Index(streamName, "-")
+	if i <= 0 {
+		return nil
+	}
+
+	return streamName[:i]
+}
+
+// proophObject decodes the empty arrays PHP encodes for empty states, positions, payloads and metadata as empty objects
+func proophObject(data []byte) []byte {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		return []byte("{}")
+	}
+
+	return data
+}
+
+// encodeProjectionState encodes the state, prooph expects an object instead of null for an empty state
+func (o options) encodeProjectionState(state interface{}) ([]byte, error) {
+	if state == nil && o.prooph != nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(state)
+}
+
+// storedEventName returns the name stored for an event type
+func (o options) storedEventName(name string) string {
+	if o.prooph == nil {
+		return name
+	}
+
+	for stored, mapped := range o.prooph.EventNames {
+		if mapped == name {
+			return stored
+		}
+	}
+
+	return name
+}
+
+// lockedByColumn selects the lock owner, which prooph does not record
+func (o options) lockedByColumn() string {
+	if o.prooph != nil {
+		return `NULL`
+	}
+
+	return `locked_by`
+}
+
+// streamSchemaColumn selects the recorded stream schema, prooph databases record none
+func (o options) streamSchemaColumn() string {
+	if o.prooph != nil {
+		return `NULL`
+	}
+
+	return `stream_schema`
+}
+
+func (ps PersistenceStrategy) createProophEventStreamsTable(ctx context.Context) error {
+	_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+            no BIGINT(20) NOT NULL AUTO_INCREMENT,
+            real_stream_name VARCHAR(150) NOT NULL,
+            stream_name CHAR(%d) NOT NULL,
+            metadata JSON,
+            category VARCHAR(150),
+            PRIMARY KEY (no),
+            UNIQUE KEY ix_rsn (real_stream_name),
+            KEY ix_cat (category)
+		  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`,
+		ps.options.streamsTable(), ps.options.tableNameLength()))
+
+	return err
+}
+
+func (ps PersistenceStrategy) createProophProjectionsTable(ctx context.Context) error {
+	_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+            no BIGINT(20) NOT NULL AUTO_INCREMENT,
+            name VARCHAR(150) NOT NULL,
+            position JSON,
+            state JSON,
+            status VARCHAR(28) NOT NULL,
+            locked_until CHAR(26),
+            PRIMARY KEY (no),
+            UNIQUE KEY ix_name (name)
+          ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, ps.options.projectionsTable()))
+
+	return err
+}
+
+// detectStreamSchema derives the schema kind of a prooph stream from its table, prooph's simple stream strategy creates no aggregate columns
+func (ps PersistenceStrategy) detectStreamSchema(ctx context.Context, conn execQuerier, streamName string) (StreamSchema, error) {
+	var table string
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT stream_name FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()), streamName).Scan(&table)
+	if err == sql.ErrNoRows {
+		return StreamSchema{}, eventstore.StreamNotFound{Stream: streamName}
+	}
+	if err != nil {
+		return StreamSchema{}, err
+	}
+
+	var count int
+
+	err = conn.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND COLUMN_NAME = 'aggregate_version'`,
+		ps.options.database,
+		table,
+	).Scan(&count)
+	if err != nil {
+		return StreamSchema{}, err
+	}
+
+	if count == 0 {
+		return StreamSchema{Kind: SimpleSchema}, nil
+	}
+
+	return StreamSchema{Kind: AggregateSchema}, nil
+}
@@ -0,0 +1,229 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlProophCompatibility(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type UserRegistered struct {
+		Name string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(UserRegistered{})
+
+	opts := []mysql.Option{
+		mysql.WithEventStreamsTable("prooph_event_streams"),
+		mysql.WithProjectionsTable("prooph_projections"),
+		mysql.WithProophCompatibility(mysql.Prooph{
+			EventNames: map[string]string{`App\Model\UserRegistered`: "UserRegistered"},
+		}),
+	}
+
+	ps := mysql.NewPersistenceStrategy(db, opts...)
+	pm := mysql.NewProjectionManager(db, opts...)
+	eventStore := eventstore.NewEventStore(ps)
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.ExecContext(ctx, "DROP TABLE IF EXISTS prooph_event_streams, prooph_projections")
+
+	t.Run("Create streams with their prooph category", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "user-123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "user-123")
+
+		var category sql.NullString
+
+		err = db.QueryRowContext(ctx, "SELECT category FROM prooph_event_streams WHERE real_stream_name = ?", "user-123").Scan(&category)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if category.String != "user" {
+			t.Errorf("Expected category user, got %v", category)
+		}
+	})
+
+	t.Run("Map stored event names", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "user-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "user-stream")
+
+		err = eventStore.AppendTo(ctx, "user-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), UserRegistered{Name: "Jane"}, nil, time.Now()).WithAggregateType(`App\Model\User`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var name string
+
+		err = db.QueryRowContext(ctx, "SELECT event_name FROM "+mysql.GenerateTableName("user-stream")+" WHERE no = 1").Scan(&name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != `App\Model\UserRegistered` {
+			t.Errorf("Expected the prooph event name, got %s", name)
+		}
+
+		it, err := eventStore.Load(ctx, "user-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 1 || list[0].Payload().(UserRegistered).Name != "Jane" {
+			t.Errorf("Expected the registered event type, got %v", list)
+		}
+	})
+
+	t.Run("Detect prooph simple streams", func(t *testing.T) {
+		table := mysql.GenerateTableName("log-stream")
+
+		_, err := db.ExecContext(ctx, "CREATE TABLE "+table+" (no BIGINT(20) NOT NULL AUTO_INCREMENT, event_id CHAR(36) NOT NULL, event_name VARCHAR(100) NOT NULL, payload JSON NOT NULL, metadata JSON NOT NULL, created_at DATETIME(6) NOT NULL, PRIMARY KEY (no), UNIQUE KEY ix_event_id (event_id))")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO prooph_event_streams (real_stream_name, stream_name, metadata, category) VALUES (?, ?, '[]', 'log')", "log-stream", table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "log-stream")
+
+		schema, err := ps.FetchStreamSchema(ctx, "log-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.Kind != mysql.SimpleSchema {
+			t.Errorf("Expected simple schema, got %s", schema.Kind)
+		}
+	})
+
+	t.Run("Read empty PHP arrays of prooph streams", func(t *testing.T) {
+		type UserLoggedOut struct{}
+
+		tr.RegisterEvents(UserLoggedOut{})
+
+		table := mysql.GenerateTableName("session-stream")
+
+		_, err := db.ExecContext(ctx, "CREATE TABLE "+table+" (no BIGINT(20) NOT NULL AUTO_INCREMENT, event_id CHAR(36) NOT NULL, event_name VARCHAR(100) NOT NULL, payload JSON NOT NULL, metadata JSON NOT NULL, created_at DATETIME(6) NOT NULL, PRIMARY KEY (no), UNIQUE KEY ix_event_id (event_id))")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO prooph_event_streams (real_stream_name, stream_name, metadata, category) VALUES (?, ?, '[]', 'session')", "session-stream", table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "session-stream")
+
+		_, err = db.ExecContext(ctx, "INSERT INTO "+table+" (event_id, event_name, payload, metadata, created_at) VALUES (?, 'UserLoggedOut', '[]', '[]', ?)", uuid.NewV4().String(), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metadata, err := ps.FetchStreamMetadata(ctx, "session-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(metadata) != 0 {
+			t.Errorf("Expected empty stream metadata, got %v", metadata)
+		}
+
+		streams, err := ps.ListStreams(ctx, mysql.StreamFilter{Name: "session-stream"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 {
+			t.Errorf("Expected the listed stream, got %v", streams)
+		}
+
+		it, err := eventStore.Load(ctx, "session-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("Expected the stored event, got %v", list)
+		}
+		if _, ok := list[0].Payload().(UserLoggedOut); !ok {
+			t.Errorf("Expected the registered event type, got %v", list[0].Payload())
+		}
+	})
+
+	t.Run("Load prooph projections and locks", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "INSERT INTO prooph_projections (name, position, state, status, locked_until) VALUES (?, '[]', '[]', 'idle', NULL)", "user_list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pm.DeleteProjection(ctx, "user_list")
+
+		positions, state, err := pm.LoadProjection(ctx, "user_list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := state.(map[string]interface{}); !ok || len(positions) != 0 {
+			t.Errorf("Expected empty state object and positions, got %v and %v", state, positions)
+		}
+
+		err = pm.AcquireLock(ctx, "user_list", "go-1", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pm.AcquireLock(ctx, "user_list", "php-1", time.Minute)
+		if _, ok := err.(mysql.ProjectionLocked); !ok {
+			t.Fatalf("Expected ProjectionLocked, got %v", err)
+		}
+
+		err = pm.RenewLock(ctx, "user_list", "go-1", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pm.ReleaseLock(ctx, "user_list", "go-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var lockedUntil sql.NullString
+
+		err = db.QueryRowContext(ctx, "SELECT locked_until FROM prooph_projections WHERE name = ?", "user_list").Scan(&lockedUntil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lockedUntil.Valid {
+			t.Errorf("Expected released lock, got %s", lockedUntil.String)
+		}
+	})
+}
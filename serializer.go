@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// CodecMetadataKey is the metadata key which records the Serializer of the event payload in the stored metadata,
+	// it is omitted for the default codec and removed from loaded events
+	CodecMetadataKey = "_codec"
+
+	defaultCodec = "json"
+	base64Suffix = "+base64"
+)
+
+// Serializer encodes and decodes event payloads and metadata
+type Serializer interface {
+	// Name identifies the codec, it is recorded with every appended event
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONSerializer is the default Serializer based on encoding/json
+type JSONSerializer struct {
+	// UseNumber decodes numbers as json.Number instead of float64
+	UseNumber bool
+}
+
+func (s JSONSerializer) Name() string {
+	return defaultCodec
+}
+
+func (s JSONSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (s JSONSerializer) Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if s.UseNumber {
+		decoder.UseNumber()
+	}
+
+	return decoder.Decode(v)
+}
+
+// serializers encode payloads with the configured Serializer and decode them with the Serializer recorded per event.
+// Payloads and metadata are stored in JSON columns, so binary payloads are stored as base64 encoded JSON strings
+type serializers struct {
+	payload  Serializer
+	metadata Serializer
+	codecs   map[string]Serializer
+}
+
+func (s serializers) register(serializer Serializer) {
+	s.codecs[serializer.Name()] = serializer
+}
+
+func (s serializers) encodePayload(payload interface{}) ([]byte, string, error) {
+	data, err := s.payload.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if json.Valid(data) {
+		return data, s.payload.Name(), nil
+	}
+
+	data, err = json.Marshal(base64.StdEncoding.EncodeToString(data))
+
+	return data, s.payload.Name() + base64Suffix, err
+}
+
+func (s serializers) decodePayload(data []byte, codec string, payload interface{}) error {
+	if codec == "" {
+		codec = defaultCodec
+	}
+
+	if strings.HasSuffix(codec, base64Suffix) {
+		codec = strings.TrimSuffix(codec, base64Suffix)
+
+		var encoded string
+
+		err := json.Unmarshal(data, &encoded)
+		if err != nil {
+			return err
+		}
+
+		data, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+	}
+
+	serializer, ok := s.codecs[codec]
+	if !ok {
+		return fmt.Errorf("No Serializer registered for codec %s", codec)
+	}
+
+	return serializer.Unmarshal(data, payload)
+}
+
+func (s serializers) encodeMetadata(metadata map[string]interface{}) ([]byte, error) {
+	data, err := s.metadata.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("Metadata Serializer %s has to produce JSON", s.metadata.Name())
+	}
+
+	return data, nil
+}
+
+func (s serializers) decodeMetadata(data []byte) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+
+	err := s.metadata.Unmarshal(data, &metadata)
+
+	return metadata, err
+}
+
+func newSerializers() serializers {
+	s := serializers{
+		payload:  JSONSerializer{},
+		metadata: JSONSerializer{},
+		codecs:   map[string]Serializer{},
+	}
+	s.register(s.payload)
+
+	return s
+}
@@ -0,0 +1,113 @@
+package mysql_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+type gobSerializer struct{}
+
+func (s gobSerializer) Name() string {
+	return "gob"
+}
+
+func (s gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	err := gob.NewEncoder(&buffer).Encode(v)
+
+	return buffer.Bytes(), err
+}
+
+func (s gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func Test_MysqlSerializer(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type SerializedEvent struct {
+		Foo string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(SerializedEvent{})
+
+	jsonStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(
+		db,
+		mysql.WithSerializers(gobSerializer{}),
+		mysql.WithMetadataSerializer(mysql.JSONSerializer{UseNumber: true}),
+	))
+	gobStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db, mysql.WithSerializer(gobSerializer{})))
+
+	err = jsonStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("Load a stream with mixed codecs", func(t *testing.T) {
+		err := jsonStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer jsonStore.DeleteStream(ctx, "foo-stream")
+
+		err = jsonStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), SerializedEvent{Foo: "json"}, nil, time.Now()).WithAddedMetadata("counter", 9007199254740993),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = gobStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), SerializedEvent{Foo: "gob"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := jsonStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2 {
+			t.Fatalf("Expected 2 events, got %d", len(list))
+		}
+
+		if list[0].Payload().(SerializedEvent).Foo != "json" || list[1].Payload().(SerializedEvent).Foo != "gob" {
+			t.Errorf("Unexpected payloads %v, %v", list[0].Payload(), list[1].Payload())
+		}
+
+		if _, ok := list[1].Metadata()[mysql.CodecMetadataKey]; ok {
+			t.Errorf("Expected no codec in the loaded metadata, got %v", list[1].Metadata()[mysql.CodecMetadataKey])
+		}
+
+		if counter, ok := list[0].Metadata()["counter"].(json.Number); !ok || counter.String() != "9007199254740993" {
+			t.Errorf("Expected precise json.Number metadata, got %v", list[0].Metadata()["counter"])
+		}
+	})
+}
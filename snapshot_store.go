@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+const SnapshotsTable = "snapshots"
+
+// Snapshot is the serialized state of an aggregate at the given aggregate version
+type Snapshot struct {
+	AggregateType    string
+	AggregateID      string
+	AggregateVersion int
+	State            interface{}
+	CreatedAt        time.Time
+}
+
+// SnapshotStore saves and loads the latest Snapshot per aggregate, the state is encoded with the configured payload Serializer
+type SnapshotStore struct {
+	db      *sql.DB
+	options options
+}
+
+func (s SnapshotStore) CreateSnapshotsTable(ctx context.Context) error {
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, s.options.snapshotsTable())).Err()
+	if err == nil {
+		return nil
+	}
+
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+            aggregate_type VARCHAR(150) NOT NULL,
+            aggregate_id CHAR(36) NOT NULL,
+            aggregate_version INT(11) UNSIGNED NOT NULL,
+            codec VARCHAR(50) NOT NULL,
+            state LONGBLOB NOT NULL,
+            created_at DATETIME(6) NOT NULL,
+            PRIMARY KEY (aggregate_type, aggregate_id)
+		  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, s.options.snapshotsTable()))
+
+	return err
+}
+
+// Save stores the Snapshot, an existing Snapshot of the aggregate is only replaced by a newer aggregate version
+func (s SnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	state, err := s.options.serializers.payload.Marshal(snapshot.State)
+	if err != nil {
+		return err
+	}
+
+	createdAt := snapshot.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	// aggregate_version has to be updated last, the other assignments compare against its current value
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (aggregate_type, aggregate_id, aggregate_version, codec, state, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			codec = IF(VALUES(aggregate_version) > aggregate_version, VALUES(codec), codec),
+			state = IF(VALUES(aggregate_version) > aggregate_version, VALUES(state), state),
+			created_at = IF(VALUES(aggregate_version) > aggregate_version, VALUES(created_at), created_at),
+			aggregate_version = GREATEST(aggregate_version, VALUES(aggregate_version))`, s.options.snapshotsTable()),
+		snapshot.AggregateType,
+		snapshot.AggregateID,
+		snapshot.AggregateVersion,
+		s.options.serializers.payload.Name(),
+		state,
+		createdAt,
+	)
+
+	return err
+}
+
+// Load the latest Snapshot of the aggregate and decode its state into the given pointer
+func (s SnapshotStore) Load(ctx context.Context, aggregateType, aggregateID string, state interface{}) (Snapshot, error) {
+	snapshot := Snapshot{AggregateType: aggregateType, AggregateID: aggregateID}
+
+	var codec string
+	var data []byte
+
+	err := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT aggregate_version, codec, state, created_at FROM %s WHERE aggregate_type = ? AND aggregate_id = ?`, s.options.snapshotsTable()),
+		aggregateType,
+		aggregateID,
+	).Scan(&snapshot.AggregateVersion, &codec, &data, &snapshot.CreatedAt)
+	if err == sql.ErrNoRows {
+		return snapshot, SnapshotNotFound{AggregateType: aggregateType, AggregateID: aggregateID}
+	}
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = s.options.serializers.decodePayload(data, codec, state)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.State = state
+
+	return snapshot, nil
+}
+
+// Delete the Snapshot of the aggregate
+func (s SnapshotStore) Delete(ctx context.Context, aggregateType, aggregateID string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE aggregate_type = ? AND aggregate_id = ?`, s.options.snapshotsTable()), aggregateType, aggregateID)
+
+	return err
+}
+
+// LoadAggregate loads the latest Snapshot of the aggregate and an iterator over its events in the given EventStream after the Snapshot version
+// Without a Snapshot the returned Snapshot has version 0 and the iterator contains all events of the aggregate
+func (s SnapshotStore) LoadAggregate(ctx context.Context, streamName, aggregateType, aggregateID string, state interface{}) (Snapshot, eventstore.DomainEventIterator, error) {
+	snapshot, err := s.Load(ctx, aggregateType, aggregateID, state)
+	if _, ok := err.(SnapshotNotFound); ok {
+		err = nil
+	}
+	if err != nil {
+		return snapshot, nil, err
+	}
+
+	ps := PersistenceStrategy{db: s.db, options: s.options}
+
+	it, err := ps.LoadAggregate(ctx, streamName, aggregateType, aggregateID, snapshot.AggregateVersion)
+
+	return snapshot, it, err
+}
+
+func NewSnapshotStore(db *sql.DB, opts ...Option) SnapshotStore {
+	return SnapshotStore{db: db, options: newOptions(opts)}
+}
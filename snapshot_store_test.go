@@ -0,0 +1,147 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type CounterIncreased struct {
+		Amount int
+	}
+
+	type CounterState struct {
+		Total int
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(CounterIncreased{})
+
+	snapshots := mysql.NewSnapshotStore(db)
+	eventStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db))
+
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = snapshots.CreateSnapshotsTable(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("Keep the newest Snapshot", func(t *testing.T) {
+		aggregateID := uuid.NewV4().String()
+		defer snapshots.Delete(ctx, "Counter", aggregateID)
+
+		for _, version := range []int{2, 3, 1} {
+			err := snapshots.Save(ctx, mysql.Snapshot{
+				AggregateType:    "Counter",
+				AggregateID:      aggregateID,
+				AggregateVersion: version,
+				State:            CounterState{Total: version * 10},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var state CounterState
+
+		snapshot, err := snapshots.Load(ctx, "Counter", aggregateID, &state)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if snapshot.AggregateVersion != 3 || state.Total != 30 {
+			t.Errorf("Expected Snapshot version 3 with total 30, got version %d with total %d", snapshot.AggregateVersion, state.Total)
+		}
+
+		err = snapshots.Delete(ctx, "Counter", aggregateID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = snapshots.Load(ctx, "Counter", aggregateID, &state)
+		if _, ok := err.(mysql.SnapshotNotFound); !ok {
+			t.Errorf("Expected SnapshotNotFound error, got %v", err)
+		}
+	})
+
+	t.Run("Load aggregate events after the Snapshot", func(t *testing.T) {
+		err := eventStore.CreateStream(ctx, "counter-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "counter-stream")
+
+		aggregateID := uuid.NewV4()
+		defer snapshots.Delete(ctx, "Counter", aggregateID.String())
+
+		events := []eventstore.DomainEvent{}
+		for version := 1; version <= 5; version++ {
+			events = append(events, eventstore.NewDomainEvent(aggregateID, CounterIncreased{Amount: version}, nil, time.Now()).
+				WithAggregateType("Counter").
+				WithVersion(version))
+		}
+		events = append(events, eventstore.NewDomainEvent(uuid.NewV4(), CounterIncreased{Amount: 100}, nil, time.Now()).
+			WithAggregateType("Counter").
+			WithVersion(1))
+
+		err = eventStore.AppendTo(ctx, "counter-stream", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = snapshots.Save(ctx, mysql.Snapshot{
+			AggregateType:    "Counter",
+			AggregateID:      aggregateID.String(),
+			AggregateVersion: 3,
+			State:            CounterState{Total: 6},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var state CounterState
+
+		snapshot, it, err := snapshots.LoadAggregate(ctx, "counter-stream", "Counter", aggregateID.String(), &state)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if snapshot.AggregateVersion != 3 || len(list) != 2 {
+			t.Fatalf("Expected Snapshot version 3 followed by 2 events, got version %d and %d events", snapshot.AggregateVersion, len(list))
+		}
+
+		for _, event := range list {
+			state.Total += event.Payload().(CounterIncreased).Amount
+		}
+
+		if state.Total != 15 {
+			t.Errorf("Expected total 15, got %d", state.Total)
+		}
+	})
+}
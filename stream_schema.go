@@ -0,0 +1,211 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+// SchemaKind defines the table layout of an EventStream
+type SchemaKind string
+
+const (
+	// AggregateSchema stores the aggregate metadata of every event in generated columns with a unique aggregate version, the default
+	AggregateSchema SchemaKind = "aggregate"
+	// SimpleSchema stores the events without aggregate columns, for integration or log events without aggregate metadata
+	// It is only supported by the TablePerStream strategy and appends to it cannot check an expected version
+	SimpleSchema SchemaKind = "simple"
+)
+
+// MetadataType is the column type of an indexed metadata field
+type MetadataType string
+
+const (
+	// MetadataString stores the field as VARCHAR, values longer than the column fail the append
+	MetadataString MetadataType = "string"
+	// MetadataInt stores the field as BIGINT
+	MetadataInt MetadataType = "int"
+)
+
+// MetadataIndex materializes a metadata field as a stored generated column with a secondary index,
+// matchers filtering the field use the column instead of extracting it from the metadata
+type MetadataIndex struct {
+	// Field is the path of the metadata field, nested fields are separated by dots
+	Field string       `json:"field"`
+	Type  MetadataType `json:"type"`
+	// Length of MetadataString columns, the default is 255
+	Length int `json:"length,omitempty"`
+}
+
+var metadataFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// column returns the name of the generated column
+func (i MetadataIndex) column() string {
+	return "meta_" + strings.ReplaceAll(i.Field, ".", "__")
+}
+
+func (i MetadataIndex) definition() string {
+	path := "$." + i.Field
+
+	if i.Type == MetadataInt {
+		return fmt.Sprintf(`%s BIGINT GENERATED ALWAYS AS (JSON_EXTRACT(metadata, '%s')) STORED`, i.column(), path)
+	}
+
+	length := i.Length
+	if length <= 0 {
+		length = 255
+	}
+
+	return fmt.Sprintf(`%s VARCHAR(%d) COLLATE utf8mb4_bin GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(metadata, '%s'))) STORED`, i.column(), length, path)
+}
+
+func (i MetadataIndex) validate() error {
+	if !metadataFieldPattern.MatchString(i.Field) || strings.HasPrefix(i.Field, "_aggregate_") {
+		return fmt.Errorf("Invalid indexed metadata field %s", i.Field)
+	}
+	// MySQL limits identifiers to 64 characters, the key name adds the ix_ prefix
+	if len(i.column()) > 61 {
+		return fmt.Errorf("Indexed metadata field %s is too long", i.Field)
+	}
+	if i.Type != MetadataString && i.Type != MetadataInt {
+		return fmt.Errorf("Unknown type %s of indexed metadata field %s", i.Type, i.Field)
+	}
+
+	return nil
+}
+
+// StreamSchema describes the table layout of an EventStream, it is recorded in the EventStreams table
+type StreamSchema struct {
+	Kind SchemaKind `json:"kind"`
+	// Indexes are the indexed metadata fields of the EventStream
+	Indexes []MetadataIndex `json:"indexes,omitempty"`
+}
+
+// columns returns the generated metadata columns of the layout
+func (s StreamSchema) columns() map[string]string {
+	columns := map[string]string{}
+
+	if s.Kind != SimpleSchema {
+		for field, column := range aggregateColumns {
+			columns[field] = column
+		}
+	}
+
+	for _, index := range s.Indexes {
+		columns[index.Field] = index.column()
+	}
+
+	return columns
+}
+
+// indexDefinitions returns the generated columns, each followed by a comma, and the keys, each preceded by a comma, of the indexed metadata fields
+func (s StreamSchema) indexDefinitions() (string, string) {
+	columns := ""
+	keys := ""
+
+	for _, index := range s.Indexes {
+		columns += index.definition() + ",\n\t\t\t"
+		keys += fmt.Sprintf(",\n\t\t\tKEY ix_%s (%s, no)", index.column(), index.column())
+	}
+
+	return columns, keys
+}
+
+func (s StreamSchema) validate(o options) error {
+	if o.prooph != nil && o.tableStrategy != TablePerStream {
+		return fmt.Errorf("The prooph compatibility mode requires the TablePerStream strategy")
+	}
+
+	if len(s.Indexes) > 0 && o.tableStrategy != TablePerStream {
+		return fmt.Errorf("Indexed metadata fields require the TablePerStream strategy")
+	}
+	// prooph databases have no column to record the indexes
+	if len(s.Indexes) > 0 && o.prooph != nil {
+		return fmt.Errorf("Indexed metadata fields are not supported in the prooph compatibility mode")
+	}
+
+	fields := map[string]bool{}
+	for _, index := range s.Indexes {
+		err := index.validate()
+		if err != nil {
+			return err
+		}
+		if fields[index.column()] {
+			return fmt.Errorf("Duplicate indexed metadata field %s", index.Field)
+		}
+		fields[index.column()] = true
+	}
+
+	switch s.Kind {
+	case AggregateSchema:
+		return nil
+	case SimpleSchema:
+		if o.tableStrategy != TablePerStream {
+			return fmt.Errorf("Schema kind %s requires the TablePerStream strategy", s.Kind)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Unknown schema kind %s", s.Kind)
+}
+
+// decodeStreamSchema decodes a recorded schema, streams created before the schema was recorded use the AggregateSchema
+func decodeStreamSchema(data []byte) (StreamSchema, error) {
+	schema := StreamSchema{Kind: AggregateSchema}
+
+	if len(data) == 0 {
+		return schema, nil
+	}
+
+	err := json.Unmarshal(data, &schema)
+
+	return schema, err
+}
+
+// FetchStreamSchema returns the recorded table layout of the EventStream
+func (ps PersistenceStrategy) FetchStreamSchema(ctx context.Context, streamName string) (StreamSchema, error) {
+	return ps.fetchStreamSchema(ctx, ps.db, streamName)
+}
+
+func (ps PersistenceStrategy) fetchStreamSchema(ctx context.Context, conn execQuerier, streamName string) (StreamSchema, error) {
+	if ps.options.prooph != nil {
+		return ps.detectStreamSchema(ctx, conn, streamName)
+	}
+
+	var data []byte
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT stream_schema FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()), streamName).Scan(&data)
+	// EventStreams tables without the stream_schema column of migration 3 only contain streams with the AggregateSchema
+	if isUnknownColumn(err) {
+		err = conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT NULL FROM %s WHERE real_stream_name = ?`, ps.options.streamsTable()), streamName).Scan(&data)
+	}
+	if err == sql.ErrNoRows {
+		return StreamSchema{}, eventstore.StreamNotFound{Stream: streamName}
+	}
+	if err != nil {
+		return StreamSchema{}, err
+	}
+
+	return decodeStreamSchema(data)
+}
+
+// addStreamSchemaColumn adds the stream_schema column to an EventStreams table created before it was recorded, like migration 3
+func (ps PersistenceStrategy) addStreamSchemaColumn(ctx context.Context) error {
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT stream_schema FROM %s LIMIT 1`, ps.options.streamsTable())).Err()
+	if !isUnknownColumn(err) {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN stream_schema JSON AFTER metadata`, ps.options.streamsTable()))
+	if isAlreadyApplied(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,249 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlStreamSchema(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type RequestLogged struct {
+		Path string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(RequestLogged{})
+
+	ps := mysql.NewPersistenceStrategy(db)
+	eventStore := eventstore.NewEventStore(ps)
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = ps.CreateStream(ctx, "log-stream", mysql.WithSchemaKind(mysql.SimpleSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eventStore.DeleteStream(ctx, "log-stream")
+
+	t.Run("Record the schema kind of the stream", func(t *testing.T) {
+		schema, err := ps.FetchStreamSchema(ctx, "log-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.Kind != mysql.SimpleSchema {
+			t.Errorf("Expected simple schema, got %s", schema.Kind)
+		}
+
+		streams, err := ps.ListStreams(ctx, mysql.StreamFilter{Name: "log-stream"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].Schema.Kind != mysql.SimpleSchema {
+			t.Errorf("Expected listed stream with simple schema, got %v", streams)
+		}
+	})
+
+	t.Run("Store events without aggregate metadata", func(t *testing.T) {
+		_, err := db.ExecContext(
+			ctx,
+			"INSERT INTO "+mysql.GenerateTableName("log-stream")+" (event_id, event_name, payload, metadata, created_at) VALUES (?, ?, ?, ?, ?)",
+			uuid.NewV4().String(),
+			"RequestLogged",
+			`{"Path": "/health"}`,
+			`{"source": "gateway"}`,
+			time.Now(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.AppendTo(ctx, "log-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/orders"}, map[string]interface{}{"source": "api"}, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/orders"}, map[string]interface{}{"source": "api"}, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		matcher := eventstore.MetadataMatcher{}
+		matcher = append(matcher, eventstore.MetadataMatch{Field: "source", Value: "api", Operation: eventstore.EqualsOperator, FieldType: eventstore.MetadataField})
+
+		it, err := eventStore.Load(ctx, "log-stream", 1, 0, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2 || list[0].Number() != 2 {
+			t.Errorf("Expected both api events, got %v", list)
+		}
+	})
+
+	t.Run("Reject expected versions and aggregate loads", func(t *testing.T) {
+		err := ps.AppendToWithExpectedVersion(ctx, "log-stream", 0, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/orders"}, nil, time.Now()),
+		})
+		if err == nil {
+			t.Error("Expected error for an expected version on a simple stream")
+		}
+
+		_, err = ps.LoadAggregate(ctx, "log-stream", "", uuid.NewV4().String(), 0)
+		if err == nil {
+			t.Error("Expected error for loading an aggregate from a simple stream")
+		}
+	})
+
+	t.Run("Simple schema requires TablePerStream", func(t *testing.T) {
+		shared := mysql.NewPersistenceStrategy(db, mysql.WithTableStrategy(mysql.SingleTable))
+
+		err := shared.CreateStream(ctx, "shared-log-stream", mysql.WithSchemaKind(mysql.SimpleSchema))
+		if err == nil {
+			shared.DeleteStream(ctx, "shared-log-stream")
+			t.Error("Expected error for a simple stream in a shared table")
+		}
+	})
+
+	t.Run("Filter indexed metadata fields", func(t *testing.T) {
+		err := ps.CreateStream(ctx, "tenant-stream", mysql.WithIndexedMetadata(
+			mysql.MetadataIndex{Field: "tenant_id", Type: mysql.MetadataString},
+			mysql.MetadataIndex{Field: "request.attempt", Type: mysql.MetadataInt},
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "tenant-stream")
+
+		schema, err := ps.FetchStreamSchema(ctx, "tenant-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.Kind != mysql.AggregateSchema || len(schema.Indexes) != 2 {
+			t.Fatalf("Expected aggregate schema with two indexes, got %v", schema)
+		}
+
+		var keys int
+
+		err = db.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME IN ('ix_meta_tenant_id', 'ix_meta_request__attempt')",
+			mysql.GenerateTableName("tenant-stream"),
+		).Scan(&keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys == 0 {
+			t.Fatal("Expected secondary indexes of the metadata fields")
+		}
+
+		err = eventStore.AppendTo(ctx, "tenant-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/a"}, map[string]interface{}{"tenant_id": "acme"}, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/b"}, map[string]interface{}{"tenant_id": "globex"}, time.Now()),
+			eventstore.NewDomainEvent(uuid.NewV4(), RequestLogged{Path: "/c"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		matcher := eventstore.MetadataMatcher{}
+		matcher = append(matcher, eventstore.MetadataMatch{Field: "tenant_id", Value: "acme", Operation: eventstore.EqualsOperator, FieldType: eventstore.MetadataField})
+
+		it, err := eventStore.Load(ctx, "tenant-stream", 1, 0, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 1 || list[0].Payload().(RequestLogged).Path != "/a" {
+			t.Errorf("Expected the acme event, got %v", list)
+		}
+	})
+
+	t.Run("Reject invalid indexed metadata fields", func(t *testing.T) {
+		err := ps.CreateStream(ctx, "invalid-stream", mysql.WithIndexedMetadata(mysql.MetadataIndex{Field: "tenant'); DROP TABLE x; --", Type: mysql.MetadataString}))
+		if err == nil {
+			eventStore.DeleteStream(ctx, "invalid-stream")
+			t.Error("Expected error for an invalid metadata field")
+		}
+	})
+	t.Run("Streams tables without recorded schemas use the AggregateSchema", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, `
+			CREATE TABLE legacy_event_streams (
+				no BIGINT(20) NOT NULL AUTO_INCREMENT,
+				real_stream_name VARCHAR(150) NOT NULL,
+				stream_name CHAR(41) NOT NULL,
+				metadata JSON,
+				PRIMARY KEY (no),
+				UNIQUE KEY ix_rsn (real_stream_name)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.ExecContext(ctx, "DROP TABLE IF EXISTS legacy_event_streams")
+
+		legacy := mysql.NewPersistenceStrategy(db, mysql.WithEventStreamsTable("legacy_event_streams"))
+
+		err = legacy.CreateStream(ctx, "legacy-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer legacy.DeleteStream(ctx, "legacy-stream")
+
+		schema, err := legacy.FetchStreamSchema(ctx, "legacy-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema.Kind != mysql.AggregateSchema {
+			t.Errorf("Expected aggregate schema, got %s", schema.Kind)
+		}
+
+		streams, err := legacy.ListStreams(ctx, mysql.StreamFilter{Name: "legacy-stream"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 1 || streams[0].Schema.Kind != mysql.AggregateSchema {
+			t.Errorf("Expected listed stream with aggregate schema, got %v", streams)
+		}
+
+		err = legacy.CreateStream(ctx, "legacy-log-stream", mysql.WithSchemaKind(mysql.SimpleSchema))
+		if err == nil {
+			legacy.DeleteStream(ctx, "legacy-log-stream")
+			t.Error("Expected error for a simple stream without the stream_schema column")
+		}
+
+		err = eventstore.NewEventStore(legacy).Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.QueryRowContext(ctx, "SELECT stream_schema FROM legacy_event_streams LIMIT 1").Err()
+		if err != nil {
+			t.Errorf("Expected stream_schema column added by Install, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+// SubscriptionOption configures a Subscription created by PersistenceStrategy.Subscribe or SubscribeFromPosition
+type SubscriptionOption func(*subscriptionOptions)
+
+type subscriptionOptions struct {
+	pollInterval time.Duration
+	batchSize    int
+	bufferSize   int
+	matcher      eventstore.MetadataMatcher
+}
+
+// WithPollInterval defines how long a caught up Subscription waits before it polls for new events, the default is 100ms
+func WithPollInterval(interval time.Duration) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		if interval > 0 {
+			o.pollInterval = interval
+		}
+	}
+}
+
+// WithBatchSize defines how many events a Subscription loads per query, the default is 100
+func WithBatchSize(size int) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithBufferSize defines how many loaded events are buffered in the events channel before loading blocks, the default is unbuffered
+func WithBufferSize(size int) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		if size >= 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
+// WithSubscriptionMatcher only delivers events matching the given metadata matcher, it applies to every subscribed stream
+func WithSubscriptionMatcher(matcher eventstore.MetadataMatcher) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.matcher = matcher
+	}
+}
+
+// Subscription delivers events over a channel, it first catches up with the stored events and then polls for new ones
+// Loading blocks while the consumer does not receive the delivered events
+type Subscription struct {
+	events chan eventstore.DomainEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Events returns the channel of delivered events, it is closed when the Subscription stops
+func (s *Subscription) Events() <-chan eventstore.DomainEvent {
+	return s.events
+}
+
+// Err waits until the Subscription stops and returns the error which stopped it, a cancellation is no error
+func (s *Subscription) Err() error {
+	<-s.done
+
+	return s.err
+}
+
+// Close stops the Subscription and waits until its events channel is closed
+func (s *Subscription) Close() error {
+	s.cancel()
+
+	return s.Err()
+}
+
+func (s *Subscription) run(ctx context.Context, ps PersistenceStrategy, queries []*streamQuery, load loadQueries, fromPosition int, o subscriptionOptions) {
+	defer close(s.done)
+	defer close(s.events)
+
+	lastPosition := fromPosition
+
+	for {
+		// the AggregateTypeTable strategy creates tables of new aggregate types while subscribed
+		if ps.options.tableStrategy == AggregateTypeTable {
+			loaded, err := load(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.err = err
+				}
+				return
+			}
+
+			queries = mergeQueries(queries, loaded)
+		}
+
+		it := ps.newIterator(ctx, queries, o.batchSize)
+		if lastPosition >= 0 {
+			it.positionsTable = ps.options.positionsTable()
+			it.lastPosition = lastPosition
+		}
+
+		for it.Next() {
+			select {
+			case s.events <- *it.current:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if it.err != nil {
+			if ctx.Err() == nil {
+				s.err = it.err
+			}
+			return
+		}
+
+		if lastPosition >= 0 {
+			lastPosition = it.lastPosition
+		}
+
+		// a full batch may be followed by further stored events, so the next batch is loaded immediately
+		if it.count == 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(o.pollInterval):
+		}
+	}
+}
+
+// loadQueries creates the queries of the subscribed streams from their initial numbers
+type loadQueries func(ctx context.Context) ([]*streamQuery, error)
+
+// mergeQueries adds the loaded queries for tables without a query yet, the existing queries keep their cursors
+func mergeQueries(queries, loaded []*streamQuery) []*streamQuery {
+	for _, l := range loaded {
+		exists := false
+		for _, q := range queries {
+			if q.streamName == l.streamName && q.tableName == l.tableName {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			queries = append(queries, l)
+		}
+	}
+
+	return queries
+}
+
+func (ps PersistenceStrategy) subscribe(ctx context.Context, load loadQueries, fromPosition int, o subscriptionOptions) (*Subscription, error) {
+	queries, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromPosition >= 0 {
+		err = ps.checkPositions(ctx, queries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &Subscription{
+		events: make(chan eventstore.DomainEvent, o.bufferSize),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go s.run(ctx, ps, queries, load, fromPosition, o)
+
+	return s, nil
+}
+
+// Subscribe delivers all events of the given stream starting with the given number, and all events appended later
+func (ps PersistenceStrategy) Subscribe(ctx context.Context, streamName string, fromNumber int, opts ...SubscriptionOption) (*Subscription, error) {
+	o := newSubscriptionOptions(opts)
+
+	return ps.subscribe(ctx, func(ctx context.Context) ([]*streamQuery, error) {
+		return ps.createQuery(ctx, streamName, fromNumber, o.matcher)
+	}, -1, o)
+}
+
+// SubscribeFromPosition delivers the events of all given streams in their global order, starting after the given global position
+// The global position of each event is available as "position" metadata to resume the Subscription later
+func (ps PersistenceStrategy) SubscribeFromPosition(ctx context.Context, fromPosition int, streamNames []string, opts ...SubscriptionOption) (*Subscription, error) {
+	if !ps.options.globalOrdering {
+		return nil, fmt.Errorf("Subscribing by global position requires the WithGlobalOrdering option")
+	}
+
+	o := newSubscriptionOptions(opts)
+
+	if fromPosition < 0 {
+		fromPosition = 0
+	}
+
+	return ps.subscribe(ctx, func(ctx context.Context) ([]*streamQuery, error) {
+		queries := make([]*streamQuery, 0, len(streamNames))
+
+		for _, streamName := range streamNames {
+			query, err := ps.createQuery(ctx, streamName, 0, o.matcher)
+			if err != nil {
+				return nil, err
+			}
+
+			queries = append(queries, query...)
+		}
+
+		return queries, nil
+	}, fromPosition, o)
+}
+
+func newSubscriptionOptions(opts []SubscriptionOption) subscriptionOptions {
+	o := subscriptionOptions{
+		pollInterval: 100 * time.Millisecond,
+		batchSize:    100,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
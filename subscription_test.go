@@ -0,0 +1,225 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlSubscription(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type TestEvent struct {
+		Foo string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(TestEvent{})
+
+	receive := func(t *testing.T, subscription *mysql.Subscription, count int) []eventstore.DomainEvent {
+		events := []eventstore.DomainEvent{}
+
+		for len(events) < count {
+			select {
+			case event, ok := <-subscription.Events():
+				if !ok {
+					t.Fatalf("Subscription stopped after %d events: %v", len(events), subscription.Err())
+				}
+				events = append(events, event)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Expected %d events, got %d", count, len(events))
+			}
+		}
+
+		return events
+	}
+
+	t.Run("Catch up and follow a stream", func(t *testing.T) {
+		ps := mysql.NewPersistenceStrategy(db)
+		eventStore := eventstore.NewEventStore(ps)
+
+		err := eventStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		events := []eventstore.DomainEvent{}
+		for i := 0; i < 5; i++ {
+			events = append(events, eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "stored"}, nil, time.Now()))
+		}
+
+		err = eventStore.AppendTo(ctx, "foo-stream", events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subscription, err := ps.Subscribe(ctx, "foo-stream", 2, mysql.WithBatchSize(2), mysql.WithPollInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		caughtUp := receive(t, subscription, 4)
+		if caughtUp[0].Number() != 2 || caughtUp[3].Number() != 5 {
+			t.Errorf("Expected events 2 to 5, got %d to %d", caughtUp[0].Number(), caughtUp[3].Number())
+		}
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "live"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		live := receive(t, subscription, 1)
+		if live[0].Payload().(TestEvent).Foo != "live" {
+			t.Errorf("Expected live event, got %v", live[0].Payload())
+		}
+
+		err = subscription.Close()
+		if err != nil {
+			t.Errorf("Expected no error on Close, got %v", err)
+		}
+
+		if _, ok := <-subscription.Events(); ok {
+			t.Error("Expected closed events channel")
+		}
+	})
+
+	t.Run("Follow streams by global position", func(t *testing.T) {
+		ps := mysql.NewPersistenceStrategy(db, mysql.WithGlobalOrdering())
+		eventStore := eventstore.NewEventStore(ps)
+
+		err := eventStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.CreateStream(ctx, "bar-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "bar-stream")
+
+		subscriptionCtx, cancel := context.WithCancel(ctx)
+
+		subscription, err := ps.SubscribeFromPosition(subscriptionCtx, 0, []string{"foo-stream", "bar-stream"}, mysql.WithPollInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, stream := range []string{"bar-stream", "foo-stream", "bar-stream"} {
+			err = eventStore.AppendTo(ctx, stream, []eventstore.DomainEvent{
+				eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: stream}, nil, time.Now()),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		events := receive(t, subscription, 3)
+		for i, stream := range []string{"bar-stream", "foo-stream", "bar-stream"} {
+			if events[i].Metadata()["stream"] != stream {
+				t.Errorf("Expected event %d of %s, got %v", i, stream, events[i].Metadata()["stream"])
+			}
+		}
+
+		cancel()
+
+		err = subscription.Err()
+		if err != nil {
+			t.Errorf("Expected no error after cancellation, got %v", err)
+		}
+	})
+	t.Run("Deliver events of slow transactions by global position", func(t *testing.T) {
+		ps := mysql.NewPersistenceStrategy(db, mysql.WithGlobalOrdering())
+		eventStore := eventstore.NewEventStore(ps)
+
+		err := eventStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.CreateStream(ctx, "bar-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "bar-stream")
+
+		subscriptionCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		subscription, err := ps.SubscribeFromPosition(subscriptionCtx, 0, []string{"foo-stream", "bar-stream"}, mysql.WithPollInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ps.WithTx(tx).AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "slow"}, nil, time.Now()),
+		})
+		if err != nil {
+			tx.Rollback()
+			t.Fatal(err)
+		}
+
+		appended := make(chan error)
+		go func() {
+			appended <- eventStore.AppendTo(ctx, "bar-stream", []eventstore.DomainEvent{
+				eventstore.NewDomainEvent(uuid.NewV4(), TestEvent{Foo: "fast"}, nil, time.Now()),
+			})
+		}()
+
+		time.Sleep(200 * time.Millisecond)
+
+		err = tx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = <-appended
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events := receive(t, subscription, 2)
+		if events[0].Payload().(TestEvent).Foo != "slow" || events[1].Payload().(TestEvent).Foo != "fast" {
+			t.Errorf("Expected the slow event before the fast one, got %v, %v", events[0].Payload(), events[1].Payload())
+		}
+	})
+}
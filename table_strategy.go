@@ -0,0 +1,235 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+const EventsTable = "events"
+
+// TableStrategy defines in which tables the events of the EventStreams are stored
+type TableStrategy int
+
+const (
+	// TablePerStream stores every EventStream in its own generated table, the default
+	TablePerStream TableStrategy = iota
+	// SingleTable stores all EventStreams in the shared EventsTable, distinguished by a stream_name column
+	// Event numbers are assigned per EventStream while the EventStream row is locked, so appends to the same EventStream are serialized
+	SingleTable
+	// AggregateTypeTable stores the events in one shared table per aggregate type, read from the _aggregate_type metadata
+	// The tables are created on the first append of their aggregate type and registered as internal "$aggregate-<type>" EventStreams,
+	// the logical EventStreams are distinguished by a stream_name column and numbered like with the SingleTable strategy
+	AggregateTypeTable
+)
+
+// aggregateTablePrefix is the prefix of the internal EventStreams registering the tables of the AggregateTypeTable strategy
+const aggregateTablePrefix = "$aggregate-"
+
+// aggregateColumnsDefinition defines the generated columns of the aggregate metadata, shared by all table layouts
+const aggregateColumnsDefinition = `aggregate_version INT(11) UNSIGNED GENERATED ALWAYS AS (JSON_EXTRACT(metadata, '$._aggregate_version')) STORED NOT NULL,
+			aggregate_id CHAR(36) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(metadata, '$._aggregate_id'))) STORED NOT NULL,
+			aggregate_type VARCHAR(150) GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(metadata, '$._aggregate_type'))) STORED NOT NULL,`
+
+// streamTable locates the rows of an EventStream
+type streamTable struct {
+	// name is the qualified name of the table
+	name string
+	// key is the generated name of the EventStream, used as stream_name in shared tables and as event_table in the EventPositionsTable
+	key string
+	// shared tables contain the rows of multiple EventStreams
+	shared bool
+}
+
+// scope prepends the condition selecting the rows of the EventStream in shared tables
+func (t streamTable) scope(wheres []string, values []interface{}) ([]string, []interface{}) {
+	if !t.shared {
+		return wheres, values
+	}
+
+	return append([]string{`stream_name = ?`}, wheres...), append([]interface{}{t.key}, values...)
+}
+
+// where returns the scoped conditions joined for a WHERE clause
+func (t streamTable) where(wheres []string, values ...interface{}) (string, []interface{}) {
+	wheres, values = t.scope(wheres, values)
+	if len(wheres) == 0 {
+		return `TRUE`, values
+	}
+
+	return strings.Join(wheres, " AND "), values
+}
+
+// insertColumns returns the columns and the placeholder of an appended row
+func (t streamTable) insertColumns() (string, string) {
+	if t.shared {
+		return `stream_name, no, event_id, event_name, payload, metadata, created_at`, `(?, ?, ?, ?, ?, ?, ?)`
+	}
+
+	return `event_id, event_name, payload, metadata, created_at`, `(?, ?, ?, ?, ?)`
+}
+
+// streamTable returns the table of the EventStream for the TablePerStream and SingleTable strategies,
+// the tables of the AggregateTypeTable strategy depend on the events and are resolved by appendTable and streamTables
+func (ps PersistenceStrategy) streamTable(streamName string) streamTable {
+	key := ps.options.generateTableName(streamName)
+
+	if ps.options.tableStrategy == SingleTable {
+		return streamTable{name: ps.options.eventsTable(), key: key, shared: true}
+	}
+
+	return streamTable{name: ps.options.qualify(key), key: key}
+}
+
+// CreateEventsTable creates the shared EventsTable of the SingleTable strategy
+func (ps PersistenceStrategy) CreateEventsTable(ctx context.Context) error {
+	err := ps.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT * FROM %s LIMIT 1`, ps.options.eventsTable())).Err()
+	if err == nil {
+		return nil
+	}
+
+	return ps.createSharedTable(ctx, ps.options.eventsTable())
+}
+
+func (ps PersistenceStrategy) createSharedTable(ctx context.Context, name string) error {
+	_, err := ps.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			stream_name CHAR(%d) COLLATE utf8mb4_bin NOT NULL,
+			no BIGINT(20) NOT NULL,
+			event_id CHAR(36) COLLATE utf8mb4_bin NOT NULL,
+			event_name VARCHAR(100) COLLATE utf8mb4_bin NOT NULL,
+			payload JSON NOT NULL,
+			metadata JSON NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			%s
+			PRIMARY KEY (stream_name, no),
+			UNIQUE KEY ix_event_id (stream_name, event_id),
+			UNIQUE KEY ix_unique_event (stream_name, aggregate_type, aggregate_id, aggregate_version),
+			KEY ix_query_aggregate (stream_name, aggregate_type, aggregate_id, no)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;`, name, ps.options.tableNameLength(), aggregateColumnsDefinition))
+
+	return err
+}
+
+// aggregateTable returns the table of the aggregate type with the rows of the EventStream
+func (ps PersistenceStrategy) aggregateTable(streamName, aggregateType string) streamTable {
+	return streamTable{
+		name:   ps.options.qualify(ps.options.generateTableName(aggregateTablePrefix + aggregateType)),
+		key:    ps.options.generateTableName(streamName),
+		shared: true,
+	}
+}
+
+// appendTable returns the table the event is appended to
+func (ps PersistenceStrategy) appendTable(streamName string, ev eventstore.DomainEvent) streamTable {
+	if ps.options.tableStrategy == AggregateTypeTable {
+		return ps.aggregateTable(streamName, fmt.Sprintf("%v", ev.Metadata()["_aggregate_type"]))
+	}
+
+	return ps.streamTable(streamName)
+}
+
+// streamTables returns all tables which may contain rows of the EventStream
+func (ps PersistenceStrategy) streamTables(ctx context.Context, conn execQuerier, streamName string) ([]streamTable, error) {
+	if ps.options.tableStrategy != AggregateTypeTable {
+		return []streamTable{ps.streamTable(streamName)}, nil
+	}
+
+	tables := []streamTable{}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT stream_name FROM %s WHERE real_stream_name LIKE ? ORDER BY no ASC`, ps.options.streamsTable()), likeEscaper.Replace(aggregateTablePrefix)+"%")
+	if err != nil {
+		return tables, err
+	}
+	defer rows.Close()
+
+	key := ps.options.generateTableName(streamName)
+
+	for rows.Next() {
+		var name string
+
+		err = rows.Scan(&name)
+		if err != nil {
+			return []streamTable{}, err
+		}
+
+		tables = append(tables, streamTable{name: ps.options.qualify(name), key: key, shared: true})
+	}
+
+	return tables, rows.Err()
+}
+
+// createAggregateTables creates the missing tables for the aggregate types of the events
+// It has to run before the append transaction, because MySQL commits a transaction implicitly on CREATE TABLE
+func (ps PersistenceStrategy) createAggregateTables(ctx context.Context, events []eventstore.DomainEvent) error {
+	if ps.options.tableStrategy != AggregateTypeTable {
+		return nil
+	}
+
+	created := map[string]bool{}
+
+	for _, ev := range events {
+		streamName := aggregateTablePrefix + fmt.Sprintf("%v", ev.Metadata()["_aggregate_type"])
+		if created[streamName] {
+			continue
+		}
+
+		exists, err := ps.HasStream(ctx, streamName)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			err = ps.createSharedTable(ctx, ps.options.qualify(ps.options.generateTableName(streamName)))
+			if err != nil {
+				return err
+			}
+
+			// concurrent appends may register the same table
+			err = ps.AddStreamToStreamsTable(ctx, streamName)
+			if _, ok := err.(eventstore.StreamAlreadyExist); err != nil && !ok {
+				return err
+			}
+		}
+
+		created[streamName] = true
+	}
+
+	return nil
+}
+
+// lastNumber locks the EventStream row until the end of the transaction and returns the highest event number of the EventStream in the given tables
+func (ps PersistenceStrategy) lastNumber(ctx context.Context, conn execQuerier, streamName string, tables []streamTable) (int, error) {
+	var no int
+
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT no FROM %s WHERE real_stream_name = ? FOR UPDATE`, ps.options.streamsTable()), streamName).Scan(&no)
+	if err == sql.ErrNoRows {
+		return 0, eventstore.StreamNotFound{Stream: streamName}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	no = 0
+
+	// the numbers are locking reads, a consistent read could return the numbers of a snapshot taken before the EventStream row was locked
+	for _, table := range tables {
+		var last int
+
+		where, values := table.where(nil)
+
+		err = conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(no), 0) FROM %s WHERE %s FOR UPDATE`, table.name, where), values...).Scan(&last)
+		if err != nil {
+			return 0, err
+		}
+
+		if last > no {
+			no = last
+		}
+	}
+
+	return no, nil
+}
@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"reflect"
+	"sync"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+// typeRegistry keeps its registered types isolated, in contrast to eventstore.NewTypeRegistry which shares one global registry
+type typeRegistry struct {
+	mu    sync.RWMutex
+	types eventstore.TypeCache
+}
+
+// GetHandlers of an aggregate, handlers are resolved by the aggregate type only and can be shared
+func (r *typeRegistry) GetHandlers(source interface{}) eventstore.HandlersCache {
+	return eventstore.NewTypeRegistry().GetHandlers(source)
+}
+
+func (r *typeRegistry) GetTypeByName(typeName string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	typeValue, ok := r.types[typeName]
+
+	return typeValue, ok
+}
+
+func (r *typeRegistry) RegisterType(source interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rawType := reflect.TypeOf(source)
+	r.types[rawType.Name()] = rawType
+}
+
+func (r *typeRegistry) RegisterAggregate(aggregate interface{}, events ...interface{}) {
+	r.RegisterType(aggregate)
+	r.RegisterEvents(events...)
+}
+
+func (r *typeRegistry) RegisterEvents(events ...interface{}) {
+	for _, event := range events {
+		r.RegisterType(event)
+	}
+}
+
+// NewTypeRegistry creates an isolated TypeRegistry to be used with WithTypeRegistry
+func NewTypeRegistry() eventstore.TypeRegistry {
+	return &typeRegistry{types: eventstore.TypeCache{}}
+}
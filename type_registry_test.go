@@ -0,0 +1,92 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlTypeRegistry(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type IsolatedEvent struct {
+		Foo string
+	}
+
+	registry := mysql.NewTypeRegistry()
+	registry.RegisterEvents(IsolatedEvent{})
+
+	t.Run("Registries are isolated", func(t *testing.T) {
+		if _, ok := registry.GetTypeByName("IsolatedEvent"); !ok {
+			t.Error("Expected registered type")
+		}
+
+		if _, ok := mysql.NewTypeRegistry().GetTypeByName("IsolatedEvent"); ok {
+			t.Error("Expected type only in its own registry")
+		}
+
+		if _, ok := eventstore.NewTypeRegistry().GetTypeByName("IsolatedEvent"); ok {
+			t.Error("Expected type not in the global registry")
+		}
+	})
+
+	t.Run("Load with injected TypeRegistry", func(t *testing.T) {
+		ps := mysql.NewPersistenceStrategy(db, mysql.WithTypeRegistry(registry))
+		eventStore := eventstore.NewEventStore(ps)
+
+		err := eventStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), IsolatedEvent{Foo: "foo"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := ps.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Payload().(IsolatedEvent).Foo != "foo" {
+			t.Errorf("Expected decoded IsolatedEvent, got %v", list)
+		}
+
+		it, err = mysql.NewPersistenceStrategy(db, mysql.WithTypeRegistry(mysql.NewTypeRegistry())).Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = it.ToList()
+		if _, ok := err.(mysql.UnknownEventType); !ok {
+			t.Errorf("Expected an UnknownEventType error with an empty registry, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	eventstore "github.com/go-event-store/eventstore"
+)
+
+type streamAppend struct {
+	streamName      string
+	expectedVersion int
+	events          []eventstore.DomainEvent
+}
+
+// UnitOfWork collects appends to multiple EventStreams and commits them in a single transaction
+// Either all appends are committed or none of them
+type UnitOfWork struct {
+	ps      PersistenceStrategy
+	appends []streamAppend
+}
+
+// AppendTo adds an append of the events to the given EventStream without a version check
+func (u *UnitOfWork) AppendTo(streamName string, events []eventstore.DomainEvent) {
+	u.AppendToWithExpectedVersion(streamName, AnyVersion, events)
+}
+
+// AppendToWithExpectedVersion adds an append of the events which is only committed if the current version of their aggregate matches the expectedVersion
+func (u *UnitOfWork) AppendToWithExpectedVersion(streamName string, expectedVersion int, events []eventstore.DomainEvent) {
+	u.appends = append(u.appends, streamAppend{streamName: streamName, expectedVersion: expectedVersion, events: events})
+}
+
+// Commit executes all collected appends in their order within one transaction and resets the UnitOfWork on success
+// The first failing append rolls back the transaction and its error is returned, e.g. a ConcurrencyError
+// With a caller-supplied transaction of PersistenceStrategy.WithTx the appends are executed in it without committing
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	for _, a := range u.appends {
+		err := u.ps.createAggregateTables(ctx, a.events)
+		if err != nil {
+			return err
+		}
+	}
+
+	if u.ps.tx != nil {
+		err := inSavepoint(ctx, u.ps.tx, func() error {
+			return u.appendInTx(ctx, u.ps.tx)
+		})
+		if err != nil {
+			return err
+		}
+
+		u.appends = nil
+
+		return nil
+	}
+
+	tx, err := u.ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = u.appendInTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	u.appends = nil
+
+	return nil
+}
+
+func (u *UnitOfWork) appendInTx(ctx context.Context, tx *sql.Tx) error {
+	for _, a := range u.appends {
+		err := u.ps.appendInTx(ctx, tx, a.streamName, a.expectedVersion, a.events)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnitOfWork creates an empty UnitOfWork using the configuration of the PersistenceStrategy
+func (ps PersistenceStrategy) UnitOfWork() *UnitOfWork {
+	return &UnitOfWork{ps: ps}
+}
@@ -0,0 +1,106 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type MoneyWithdrawn struct {
+		Amount int
+	}
+
+	type MoneyDeposited struct {
+		Amount int
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(MoneyWithdrawn{}, MoneyDeposited{})
+
+	ps := mysql.NewPersistenceStrategy(db)
+	eventStore := eventstore.NewEventStore(ps)
+	err = eventStore.Install(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+
+	countEvents := func(t *testing.T, streamName string) int {
+		it, err := ps.Load(ctx, streamName, 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(list)
+	}
+
+	for _, stream := range []string{"account-a", "account-b"} {
+		err = eventStore.CreateStream(ctx, stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, stream)
+	}
+
+	accountA := uuid.NewV4()
+	accountB := uuid.NewV4()
+
+	t.Run("Commit appends to multiple streams", func(t *testing.T) {
+		uow := ps.UnitOfWork()
+		uow.AppendToWithExpectedVersion("account-a", 0, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(accountA, MoneyWithdrawn{Amount: 10}, nil, time.Now()).WithAggregateType("Account").WithVersion(1),
+		})
+		uow.AppendToWithExpectedVersion("account-b", 0, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(accountB, MoneyDeposited{Amount: 10}, nil, time.Now()).WithAggregateType("Account").WithVersion(1),
+		})
+
+		err := uow.Commit(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if countEvents(t, "account-a") != 1 || countEvents(t, "account-b") != 1 {
+			t.Error("Expected one event in each stream")
+		}
+	})
+
+	t.Run("Roll back all appends on a conflict", func(t *testing.T) {
+		uow := ps.UnitOfWork()
+		uow.AppendToWithExpectedVersion("account-a", 1, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(accountA, MoneyWithdrawn{Amount: 5}, nil, time.Now()).WithAggregateType("Account").WithVersion(2),
+		})
+		uow.AppendToWithExpectedVersion("account-b", 0, []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(accountB, MoneyDeposited{Amount: 5}, nil, time.Now()).WithAggregateType("Account").WithVersion(2),
+		})
+
+		err := uow.Commit(ctx)
+		if e, ok := err.(mysql.ConcurrencyError); !ok || e.Stream != "account-b" || e.ActualVersion != 1 {
+			t.Fatalf("Expected ConcurrencyError on account-b, got %v", err)
+		}
+
+		if countEvents(t, "account-a") != 1 || countEvents(t, "account-b") != 1 {
+			t.Error("Expected the append to account-a to be rolled back")
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersionMetadataKey is the metadata key of the event schema version, events without it have version 1
+const SchemaVersionMetadataKey = "_schema_version"
+
+// RawEvent is a persisted event before its payload and metadata are decoded
+// It is also the payload of unknown events loaded with UnknownEventRaw
+type RawEvent struct {
+	EventID   string
+	Number    int
+	Name      string
+	Payload   []byte
+	Metadata  []byte
+	CreatedAt time.Time
+	Stream    string
+}
+
+// SchemaVersion of the event, read from its metadata
+func (e RawEvent) SchemaVersion() int {
+	var metadata struct {
+		SchemaVersion *int `json:"_schema_version"`
+	}
+
+	if json.Unmarshal(e.Metadata, &metadata) != nil || metadata.SchemaVersion == nil {
+		return 1
+	}
+
+	return *metadata.SchemaVersion
+}
+
+// WithSchemaVersion create a copy of the event with the given schema version
+func (e RawEvent) WithSchemaVersion(version int) (RawEvent, error) {
+	return e.WithAddedMetadata(SchemaVersionMetadataKey, version)
+}
+
+// WithAddedMetadata create a copy of the event with the given additional metadata
+func (e RawEvent) WithAddedMetadata(name string, value interface{}) (RawEvent, error) {
+	metadata := map[string]json.RawMessage{}
+
+	err := json.Unmarshal(e.Metadata, &metadata)
+	if err != nil {
+		return e, err
+	}
+
+	metadata[name], err = json.Marshal(value)
+	if err != nil {
+		return e, err
+	}
+
+	e.Metadata, err = json.Marshal(metadata)
+
+	return e, err
+}
+
+// UpcastFunc transforms an event of an outdated schema version into one or more events of a newer schema version
+// It can rename the event, rewrite its payload and metadata or split it into several events
+type UpcastFunc func(event RawEvent) ([]RawEvent, error)
+
+type upcasterKey struct {
+	name    string
+	version int
+}
+
+// Upcasters is a chain of UpcastFuncs keyed by event name and schema version
+// It is applied to every loaded event before its payload is decoded
+type Upcasters struct {
+	upcasters map[upcasterKey]UpcastFunc
+}
+
+// Register an UpcastFunc for all events with the given name and schema version
+func (u *Upcasters) Register(eventName string, schemaVersion int, upcast UpcastFunc) {
+	u.upcasters[upcasterKey{name: eventName, version: schemaVersion}] = upcast
+}
+
+// Upcast applies the registered UpcastFuncs until no one matches the resulting events
+// It fails if an UpcastFunc returns an event whose name and schema version was already upcasted before in the chain
+func (u *Upcasters) Upcast(event RawEvent) ([]RawEvent, error) {
+	return u.upcast(event, map[upcasterKey]bool{})
+}
+
+func (u *Upcasters) upcast(event RawEvent, chain map[upcasterKey]bool) ([]RawEvent, error) {
+	key := upcasterKey{name: event.Name, version: event.SchemaVersion()}
+
+	upcast, ok := u.upcasters[key]
+	if !ok {
+		return []RawEvent{event}, nil
+	}
+
+	upcasted, err := upcast(event)
+	if err != nil {
+		return []RawEvent{}, err
+	}
+
+	chain[key] = true
+	defer delete(chain, key)
+
+	events := make([]RawEvent, 0, len(upcasted))
+
+	for _, ev := range upcasted {
+		if ev.Name == key.name && ev.SchemaVersion() == key.version {
+			return []RawEvent{}, fmt.Errorf("Upcaster of %s version %d has to change the event name or schema version", key.name, key.version)
+		}
+
+		if chain[upcasterKey{name: ev.Name, version: ev.SchemaVersion()}] {
+			return []RawEvent{}, fmt.Errorf("Upcaster of %s version %d returns %s version %d which was already upcasted", key.name, key.version, ev.Name, ev.SchemaVersion())
+		}
+
+		result, err := u.upcast(ev, chain)
+		if err != nil {
+			return []RawEvent{}, err
+		}
+
+		events = append(events, result...)
+	}
+
+	return events, nil
+}
+
+func NewUpcasters() *Upcasters {
+	return &Upcasters{upcasters: map[upcasterKey]UpcastFunc{}}
+}
@@ -0,0 +1,144 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	eventstore "github.com/go-event-store/eventstore"
+	mysql "github.com/go-event-store/mysql"
+	_ "github.com/go-sql-driver/mysql"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Test_MysqlUpcasters(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", "user:password@/event-store?parseTime=true")
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+
+	type NameChanged struct {
+		FirstName string
+		LastName  string
+	}
+
+	type FirstNameChanged struct {
+		FirstName string
+	}
+
+	type LastNameChanged struct {
+		LastName string
+	}
+
+	tr := eventstore.NewTypeRegistry()
+	tr.RegisterEvents(FirstNameChanged{}, LastNameChanged{})
+
+	upcasters := mysql.NewUpcasters()
+	upcasters.Register("NameChanged", 1, func(event mysql.RawEvent) ([]mysql.RawEvent, error) {
+		var payload NameChanged
+
+		err := json.Unmarshal(event.Payload, &payload)
+		if err != nil {
+			return nil, err
+		}
+
+		first, err := event.WithSchemaVersion(2)
+		if err != nil {
+			return nil, err
+		}
+		first.Name = "FirstNameChanged"
+		first.Payload, _ = json.Marshal(FirstNameChanged{FirstName: payload.FirstName})
+
+		last := first
+		last.Name = "LastNameChanged"
+		last.Payload, _ = json.Marshal(LastNameChanged{LastName: payload.LastName})
+
+		return []mysql.RawEvent{first, last}, nil
+	})
+
+	t.Run("Split an outdated event", func(t *testing.T) {
+		events, err := upcasters.Upcast(mysql.RawEvent{
+			Name:     "NameChanged",
+			Payload:  []byte(`{"FirstName": "Rudi", "LastName": "Ratlos"}`),
+			Metadata: []byte(`{"_aggregate_version": 1}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) != 2 || events[0].Name != "FirstNameChanged" || events[1].Name != "LastNameChanged" {
+			t.Fatalf("Expected split events, got %v", events)
+		}
+
+		if events[1].SchemaVersion() != 2 {
+			t.Errorf("Expected schema version 2, got %d", events[1].SchemaVersion())
+		}
+	})
+
+	t.Run("Fail on an upcaster cycle", func(t *testing.T) {
+		cyclic := mysql.NewUpcasters()
+		cyclic.Register("A", 1, func(event mysql.RawEvent) ([]mysql.RawEvent, error) {
+			event.Name = "B"
+			return []mysql.RawEvent{event}, nil
+		})
+		cyclic.Register("B", 1, func(event mysql.RawEvent) ([]mysql.RawEvent, error) {
+			event.Name = "A"
+			return []mysql.RawEvent{event}, nil
+		})
+
+		_, err := cyclic.Upcast(mysql.RawEvent{
+			Name:     "A",
+			Payload:  []byte(`{}`),
+			Metadata: []byte(`{}`),
+		})
+		if err == nil {
+			t.Fatal("Expected an error for the upcaster cycle")
+		}
+	})
+
+	t.Run("Load upcasted events", func(t *testing.T) {
+		eventStore := eventstore.NewEventStore(mysql.NewPersistenceStrategy(db, mysql.WithUpcasters(upcasters)))
+		err := eventStore.Install(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = eventStore.CreateStream(ctx, "foo-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eventStore.DeleteStream(ctx, "foo-stream")
+
+		err = eventStore.AppendTo(ctx, "foo-stream", []eventstore.DomainEvent{
+			eventstore.NewDomainEvent(uuid.NewV4(), NameChanged{FirstName: "Rudi", LastName: "Ratlos"}, nil, time.Now()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		it, err := eventStore.Load(ctx, "foo-stream", 1, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := it.ToList()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list) != 2 {
+			t.Fatalf("Expected 2 upcasted events, got %d", len(list))
+		}
+
+		if list[0].Payload().(FirstNameChanged).FirstName != "Rudi" || list[1].Payload().(LastNameChanged).LastName != "Ratlos" {
+			t.Errorf("Unexpected upcasted payloads %v, %v", list[0].Payload(), list[1].Payload())
+		}
+	})
+}